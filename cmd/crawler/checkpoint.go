@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"GoCrawler/internal/crawler"
+)
+
+// checkpoint is the resumable state of an interrupted standalone crawl.
+type checkpoint struct {
+	Seed       string             `json:"seed"`
+	CrawlID    int64              `json:"crawl_id,omitempty"`
+	SavedAt    time.Time          `json:"saved_at"`
+	Queue      []crawler.CrawlJob `json:"queue"`
+	Visited    []string           `json:"visited"`
+	Images     []imageJob         `json:"images"`
+	SeenImages []string           `json:"seen_images"`
+}
+
+// sameSeed reports whether two --url values name the same seed.
+func sameSeed(a, b string) bool {
+	na, errA := crawler.NormalizeURL(a, a)
+	nb, errB := crawler.NormalizeURL(b, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return na == nb
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func (cp *checkpoint) save(path string) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"GoCrawler/internal/cluster"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/metrics"
+	"GoCrawler/internal/storage"
+)
+
+// runCoordinator owns the frontier of a distributed crawl and serves leases
+// to `crawler node` processes; it fetches nothing itself.
+func runCoordinator(args []string) {
+	fs := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	var opts crawlOptions
+	opts.register(fs)
+	timeout := fs.Int("timeout", 120, "Global timeout in seconds (default: 120)")
+	listen := fs.String("listen", ":9090", "Address to serve the coordinator API on")
+	leaseTTL := fs.Int("lease-ttl", 60, "Seconds before an unreported lease returns to the queue")
+	linger := fs.Int("linger", 10, "Seconds to keep serving after the crawl finishes so nodes see it")
+	statsPath := fs.String("stats", "crawl-stats.json", "Write the end-of-crawl statistics report here as JSON (empty disables)")
+	cfgPath := registerConfig(fs)
+	var lopts logging.Options
+	lopts.Register(fs)
+	fs.Parse(args)
+	applyLogging(lopts)
+	cfg := loadConfig(*cfgPath)
+
+	slog.Info("coordinator start", append(opts.attrs(), "timeout", *timeout, "listen", *listen, "lease_ttl", *leaseTTL)...)
+
+	if opts.startURL == "" {
+		logging.Fatal("missing required flag: --url")
+	}
+	if err := opts.validate(); err != nil {
+		logging.Fatal("invalid flags", "err", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*timeout)*time.Second)
+	defer cancel()
+
+	budget := opts.budget()
+	frontier := opts.frontier(budget)
+	slog.Debug("enqueue seed", "url", opts.startURL)
+	frontier.Add(opts.startURL, opts.maxDepth, 0)
+
+	crawls := storage.NewCrawlRepository(openStore(cfg))
+	crawlID := startCrawlRecord(crawls, &opts, 0)
+
+	coord := cluster.NewCoordinator(frontier, budget, time.Duration(*leaseTTL)*time.Second)
+	coord.CrawlID = crawlID
+	coord.Hreflang = opts.hreflang
+	mux := http.NewServeMux()
+	mux.Handle("/", coord.Handler())
+	mux.Handle("GET /metrics", coordinatorMetrics(coord).Handler())
+	srv := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("coordinator listen", "addr", *listen, "err", err)
+		}
+	}()
+	slog.Info("coordinator serving", "addr", *listen)
+
+	coord.Run(ctx)
+	outcome := "complete"
+	if ctx.Err() != nil {
+		slog.Warn("global timeout reached", "err", ctx.Err())
+		outcome = "timeout"
+	}
+	report := coord.Report(opts.startURL, outcome)
+	saveReport(crawls, crawlID, report, *statsPath)
+	report.Print(os.Stdout)
+
+	st := coord.Status()
+	slog.Info("coordinator done", "pages", st.Pages, "failed", st.Failed, "skipped", st.Skipped, "images", st.Images, "visited", st.Visited, "expired_leases", st.Expired, "late_reports", st.Late, "requeued_images", st.Requeued)
+
+	time.Sleep(time.Duration(*linger) * time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	srv.Shutdown(shutdownCtx)
+	slog.Info("crawl finished")
+}
+
+// coordinatorMetrics exposes the coordinator status as gauges, read at
+// scrape time.
+func coordinatorMetrics(coord *cluster.Coordinator) *metrics.Registry {
+	reg := metrics.NewRegistry()
+	gauge := func(name, help string, value func(cluster.Status) int) {
+		reg.NewGaugeFunc(name, help, func() float64 { return float64(value(coord.Status())) })
+	}
+	gauge("coordinator_queue_length", "Pages waiting in the frontier.", func(s cluster.Status) int { return s.Queue })
+	gauge("coordinator_visited", "URLs in the visited set.", func(s cluster.Status) int { return s.Visited })
+	gauge("coordinator_leases", "Outstanding leases.", func(s cluster.Status) int { return s.Leases })
+	gauge("coordinator_in_flight_pages", "Pages in outstanding leases.", func(s cluster.Status) int { return s.InFlight })
+	gauge("coordinator_pages", "Pages reported fetched.", func(s cluster.Status) int { return s.Pages })
+	gauge("coordinator_pages_failed", "Pages reported failed.", func(s cluster.Status) int { return s.Failed })
+	gauge("coordinator_pages_skipped", "Pages reported out of scope.", func(s cluster.Status) int { return s.Skipped })
+	gauge("coordinator_images", "Images handed out to nodes.", func(s cluster.Status) int { return s.Images })
+	gauge("coordinator_images_assigned", "Images handed out and not reported done.", func(s cluster.Status) int { return s.Assigned })
+	gauge("coordinator_images_requeued", "Images of silent nodes handed out again.", func(s cluster.Status) int { return s.Requeued })
+	gauge("coordinator_expired_leases", "Leases that expired and were requeued.", func(s cluster.Status) int { return s.Expired })
+	gauge("coordinator_late_reports", "Reports for expired leases, rejected.", func(s cluster.Status) int { return s.Late })
+	return reg
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+	"GoCrawler/internal/images"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/stats"
+	"GoCrawler/internal/storage"
+)
+
+// runCrawl is the standalone crawler: one process owns the frontier and
+// runs both worker pools.
+func runCrawl(args []string) {
+	fs := flag.NewFlagSet("crawler", flag.ExitOnError)
+	var opts crawlOptions
+	var wopts workerOptions
+	opts.register(fs)
+	wopts.register(fs)
+	timeout := fs.Int("timeout", 120, "Global timeout in seconds (default: 120)")
+	grace := fs.Int("grace", 15, "Seconds in-flight work may take to finish after SIGINT/SIGTERM")
+	checkpointPath := fs.String("checkpoint", "", "Write resumable state here when the crawl is interrupted or times out")
+	resumePath := fs.String("resume", "", "Resume from a checkpoint written by --checkpoint")
+	statsPath := fs.String("stats", "crawl-stats.json", "Write the end-of-crawl statistics report here as JSON (empty disables)")
+	showProgress := fs.Bool("progress", false, "Show a live progress dashboard (periodic summary lines when stdout is not a terminal)")
+	progressEvery := fs.Int("progress-interval", 10, "Seconds between progress lines when stdout is not a terminal")
+	cfgPath := registerConfig(fs)
+	var lopts logging.Options
+	lopts.Register(fs)
+	fs.Parse(args)
+
+	cfg := loadConfig(*cfgPath)
+	wopts.fromConfig(cfg, fs)
+	applyFetchConfig(cfg)
+
+	var prog *progress
+	if *showProgress {
+		tty := isTerminal(os.Stdout)
+		prog = newProgress(os.Stdout, tty, time.Duration(*timeout)*time.Second, wopts.workers, wopts.imgWorkers, time.Duration(*progressEvery)*time.Second)
+	}
+	if prog != nil && prog.tty && isTerminal(os.Stderr) {
+		// The dashboard replaces the per-page log lines; warnings and errors
+		// are printed above it unless a level was asked for.
+		if !flagSet(fs, "log-level") {
+			lopts.Level = "warn"
+		}
+		applyLoggingTo(lopts, prog)
+	} else {
+		applyLogging(lopts)
+	}
+
+	slog.Info("crawler start", append(append(opts.attrs(), wopts.attrs()...), "timeout", *timeout, "grace", *grace)...)
+
+	if opts.startURL == "" {
+		logging.Fatal("missing required flag: --url")
+	}
+	if err := opts.validate(); err != nil {
+		logging.Fatal("invalid flags", "err", err)
+	}
+	if err := wopts.validate(); err != nil {
+		logging.Fatal("invalid flags", "err", err)
+	}
+
+	if seed, root, ok, err := crawler.LocalSeed(opts.startURL); err != nil {
+		logging.Fatal("invalid local seed", "err", err)
+	} else if ok {
+		if wopts.replay != "" {
+			logging.Fatal("invalid flags", "err", "--replay cannot serve a local seed, which is read from disk")
+		}
+		fetch.ServeDirectory(root)
+		slog.Info("serving local directory", "dir", root, "url", seed)
+		opts.startURL = seed
+		opts.useJS = false
+	}
+
+	if wopts.replay != "" && opts.useJS {
+		slog.Warn("--js ignored: pages are served from the archive")
+		opts.useJS = false
+	}
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	// Per-host JS overrides need a browser even without --js; replayed
+	// pages never use one.
+	hostJS := wopts.replay == "" && !crawler.IsLocalURL(opts.startURL)
+	useBrowser := opts.useJS || hostJS && cfg.WantsJS()
+	if useBrowser {
+		if err := wopts.validateBrowser(); err != nil {
+			logging.Fatal("invalid flags", "err", err)
+		}
+	}
+	baseCtx, browserCancel := browserContext(useBrowser)
+	defer browserCancel()
+
+	ctx, cancel := context.WithTimeout(baseCtx, time.Duration(*timeout)*time.Second)
+	defer cancel()
+
+	var cp *checkpoint
+	if *resumePath != "" {
+		var err error
+		if cp, err = loadCheckpoint(*resumePath); err != nil {
+			logging.Fatal("load checkpoint", "path", *resumePath, "err", err)
+		}
+		if !sameSeed(cp.Seed, opts.startURL) {
+			logging.Fatal("checkpoint is for another seed", "path", *resumePath, "checkpoint_seed", cp.Seed, "url", opts.startURL)
+		}
+	}
+
+	ensureDirs(wopts.dirs)
+	store := openStore(cfg)
+	repo := storage.NewImageRepository(store)
+	crawls := storage.NewCrawlRepository(store)
+	var resumeID int64
+	if cp != nil {
+		resumeID = cp.CrawlID
+	}
+	crawlID := startCrawlRecord(crawls, &opts, resumeID)
+	graph := startGraphWriter(storage.NewPageRepository(store), crawlID)
+
+	replayDone := startReplay(wopts)
+	defer replayDone()
+	closeWARC := startWARC(wopts)
+	defer closeWARC()
+	startHostRules(cfg, wopts)
+	serveMetrics(wopts.metricsAddr, registry)
+
+	pool := crawler.NewWorkerPool(ctx, wopts.workers, 200, 200)
+	pool.Limiter = wopts.hostLimiter()
+	pool.Start(processJob(cfg, wopts, hostJS))
+	slog.Debug("started crawler worker pool", "workers", wopts.workers)
+	prog.setLimiter(pool.Limiter)
+
+	budget := opts.budget()
+	tracker := newImageTracker()
+	collector := stats.NewCollector()
+
+	imageJobs, imgWG := startImageWorkers(ctx, wopts.imgWorkers, time.Duration(wopts.imgTimeout)*time.Second, wopts.dirs, repo,
+		func(job imageJob, meta *images.ImageMetadata, err error) {
+			if meta != nil {
+				budget.AddBytes(meta.Size)
+			}
+			recordImage(ctx, graph, job, meta, err)
+			if meta != nil {
+				prog.image(nil, true)
+			} else {
+				prog.image(err, ctx.Err() == nil)
+			}
+			// Stats count fetches: a DB error after a download is not a failed image.
+			if meta != nil {
+				collector.Image(meta.Format, meta.Size, nil)
+			} else if ctx.Err() == nil {
+				collector.Image("", 0, err)
+			}
+			tracker.done(ctx, job, err)
+		})
+
+	frontier := opts.frontier(budget)
+	inFlight := make(map[string]crawler.CrawlJob, wopts.workers)
+
+	seenImages := make(map[string]struct{}, 8192)
+	variantImages := crawler.NewVariantImages()
+	imageBacklog := make([]imageJob, 0, 8192)
+
+	if cp != nil {
+		frontier.Restore(cp.Queue, cp.Visited)
+		for _, u := range cp.SeenImages {
+			seenImages[u] = struct{}{}
+		}
+		for _, j := range cp.Images {
+			j.CrawlID = crawlID
+			imageBacklog = append(imageBacklog, j)
+		}
+		slog.Info("resuming", "path", *resumePath, "saved_at", cp.SavedAt, "queue", len(cp.Queue), "visited", len(cp.Visited), "images", len(cp.Images))
+	} else {
+		slog.Debug("enqueue seed", "url", opts.startURL)
+		frontier.Add(opts.startURL, opts.maxDepth, 0)
+	}
+
+	var durationC <-chan time.Time
+	if budget.MaxDuration > 0 {
+		durationC = time.After(budget.MaxDuration)
+	}
+	draining := false
+
+	// stopping is set by the first signal: nothing new is dispatched and the
+	// loop only waits for in-flight pages until graceC fires.
+	stopping := false
+	var graceC <-chan time.Time
+	outcome := "complete"
+	prog.start()
+
+loop:
+	for frontier.Len() > 0 || len(inFlight) > 0 || len(imageBacklog) > 0 {
+		if stopping && len(inFlight) == 0 {
+			break
+		}
+		queueGauge.Set(float64(frontier.Len()))
+		inFlightGauge.Set(float64(len(inFlight)))
+		backlogGauge.Set(float64(len(imageBacklog)))
+		prog.setQueues(frontier.Len(), len(inFlight), len(imageBacklog))
+		if !draining {
+			if reason := budget.Exhausted(); reason != "" {
+				draining = true
+				dropped := frontier.Drop()
+				slog.Info("budget exhausted, draining", "reason", reason, "dropped_pages", dropped, "dropped_images", len(imageBacklog), "in_flight", len(inFlight))
+				collector.Skip(reason, dropped+len(imageBacklog))
+				imageBacklog = nil
+				continue
+			}
+		}
+		if frontier.Len() > 0 && !budget.PageAvailable() {
+			dropped := frontier.Drop()
+			slog.Info("budget exhausted", "reason", "max-pages", "dropped_pages", dropped)
+			collector.Skip("max-pages", dropped)
+		}
+		if len(imageBacklog) > 0 && !budget.ImageAvailable() {
+			slog.Info("budget exhausted", "reason", "max-images", "dropped_images", len(imageBacklog))
+			collector.Skip("max-images", len(imageBacklog))
+			imageBacklog = nil
+		}
+
+		var (
+			jobCh chan<- crawler.CrawlJob
+			next  crawler.CrawlJob
+
+			imgCh   chan<- imageJob
+			nextImg imageJob
+		)
+
+		if frontier.Len() > 0 && !stopping {
+			jobCh = pool.Jobs()
+			next = frontier.Peek()
+		}
+		if len(imageBacklog) > 0 && !stopping {
+			imgCh = imageJobs
+			nextImg = imageBacklog[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			if stopping {
+				outcome = "interrupted"
+			} else {
+				slog.Warn("global timeout reached", "err", ctx.Err())
+				outcome = "timeout"
+			}
+			break loop
+
+		case sig := <-sigCh:
+			if stopping {
+				slog.Warn("signal received again, aborting in-flight work", "signal", sig.String())
+				cancel()
+				continue
+			}
+			stopping = true
+			outcome = "interrupted"
+			graceC = time.After(time.Duration(*grace) * time.Second)
+			slog.Warn("signal received, stopping dispatch", "signal", sig.String(), "grace", *grace, "in_flight", len(inFlight), "queued_images", len(imageJobs))
+
+		case <-graceC:
+			slog.Warn("grace period expired, aborting in-flight work")
+			cancel()
+
+		case <-durationC:
+			durationC = nil
+
+		case jobCh <- next:
+			frontier.Pop()
+			inFlight[next.URL] = next
+			budget.UsePage()
+			prog.dispatched(next.URL)
+			slog.Debug("dispatch", "url", next.URL, "host", crawler.HostOf(next.URL), "depth", next.Depth, "queue", frontier.Len(), "in_flight", len(inFlight))
+
+		case imgCh <- nextImg:
+			imageBacklog = imageBacklog[1:]
+			tracker.dispatched(nextImg)
+			budget.UseImage()
+			prog.imageDispatched()
+			slog.Debug("image dispatch", "url", nextImg.Ref.URL, "backlog", len(imageBacklog), "queued", len(imageJobs))
+
+		case result, ok := <-pool.Results():
+			if !ok {
+				slog.Error("worker pool results closed unexpectedly")
+				outcome = "failed"
+				break loop
+			}
+			delete(inFlight, result.URL)
+			budget.AddBytes(result.Bytes)
+			observeLimit(pool.Limiter, result.URL)
+
+			if result.Err != nil {
+				if ctx.Err() == nil {
+					collector.Page(result.URL, result.Bytes, result.Fetch, result.Err)
+					observePage(result)
+					prog.page(result.URL, result.Err, true)
+					slog.Warn("page failed", "url", result.URL, "host", crawler.HostOf(result.URL), "depth", result.Depth, "duration", result.Fetch, "err", result.Err)
+					graph.fail("page", result.URL, result.Err)
+				} else {
+					// aborted, not failed: keep it for the checkpoint
+					inFlight[result.URL] = crawler.CrawlJob{URL: result.URL, Depth: result.Depth, Distance: result.Distance}
+					prog.page(result.URL, result.Err, false)
+				}
+				continue
+			}
+			collector.Page(result.URL, result.Bytes, result.Fetch, nil)
+			observePage(result)
+			prog.page(result.URL, nil, true)
+			if result.Skipped != "" {
+				collector.Skip(result.Skipped, 1)
+				slog.Info("page out of scope", "url", result.URL, "host", crawler.HostOf(result.URL), "depth", result.Depth, "reason", result.Skipped, "lang", result.Lang)
+				// the pages it links to take its place, e.g. the preferred variant
+				if !draining {
+					for _, link := range result.Links {
+						frontier.Add(link.URL, result.Depth, result.Distance)
+					}
+				}
+				continue
+			}
+			graph.add(result)
+			if opts.hreflang != "" {
+				frontier.Exclude("variant", crawler.OtherVariants(result.URL, result.Alternates)...)
+			}
+
+			slog.Info("page fetched", "url", result.URL, "host", crawler.HostOf(result.URL), "depth", result.Depth, "duration", result.Fetch, "bytes", result.Bytes, "links", len(result.Links), "images", len(result.Images), "in_flight", len(inFlight))
+
+			for _, img := range crawler.UniqueImages(result.Images) {
+				if draining || !budget.ImageAvailable() {
+					break
+				}
+				if _, ok := seenImages[img.URL]; ok {
+					continue
+				}
+				seenImages[img.URL] = struct{}{}
+				if result.Variant != "" && variantImages.Seen(result.Variant, result.URL, result.Alternates, img.URL) {
+					collector.Skip("variant-image", 1)
+					continue
+				}
+				imageBacklog = append(imageBacklog, imageJob{Ref: img, PageURL: result.URL, CrawlID: crawlID})
+			}
+			if len(result.Images) > 0 {
+				slog.Debug("image backlog", "url", result.URL, "backlog", len(imageBacklog))
+			}
+
+			if result.Depth > 0 && !draining {
+				for _, link := range crawler.UniqueLinks(result.Links) {
+					frontier.Add(link.URL, result.Depth-1, result.Distance+1)
+				}
+			}
+		}
+	}
+
+	pages, imgs, bytes, elapsed := budget.Usage()
+	slog.Info("budget used", "pages", pages, "images", imgs, "bytes", bytes, "duration", elapsed.Round(time.Second))
+
+	slog.Info("shutting down workers")
+	if outcome != "complete" {
+		// Images still buffered in the channel were never started; take them
+		// back so the workers only finish what they hold.
+	reclaim:
+		for {
+			select {
+			case j := <-imageJobs:
+				imageBacklog = append(imageBacklog, j)
+			default:
+				break reclaim
+			}
+		}
+	}
+	close(imageJobs)
+
+	imgDone := make(chan struct{})
+	go func() {
+		imgWG.Wait()
+		close(imgDone)
+	}()
+	select {
+	case <-imgDone:
+	case <-graceC:
+		slog.Warn("grace period expired, aborting image downloads")
+		cancel()
+		<-imgDone
+	case <-sigCh:
+		slog.Warn("signal received again, aborting image downloads")
+		cancel()
+		<-imgDone
+	}
+	pool.Stop()
+	graph.close()
+
+	leftImages := unfinishedImages(imageBacklog, tracker)
+	if *checkpointPath != "" && outcome != "complete" {
+		cp := &checkpoint{Seed: opts.startURL, CrawlID: crawlID, SavedAt: time.Now()}
+		cp.Queue, cp.Visited = frontier.Snapshot()
+		for _, job := range inFlight {
+			job.FollowExternal = opts.followExternal
+			job.UseJS = opts.useJS
+			job.LinkSources = opts.links
+			job.Languages = opts.languages
+			job.Hreflang = opts.hreflang
+			cp.Queue = append(cp.Queue, job)
+		}
+		cp.Images = leftImages
+		for u := range seenImages {
+			cp.SeenImages = append(cp.SeenImages, u)
+		}
+		if err := cp.save(*checkpointPath); err != nil {
+			slog.Error("write checkpoint", "path", *checkpointPath, "err", err)
+		} else {
+			slog.Info("wrote checkpoint", "path", *checkpointPath, "queue", len(cp.Queue), "images", len(cp.Images))
+		}
+	}
+
+	for reason, n := range frontier.Skipped() {
+		collector.Skip(reason, n)
+	}
+	if pool.Limiter != nil {
+		collector.Concurrency(pool.Limiter.Snapshot())
+	}
+	report := collector.Finish(opts.startURL, outcome, stats.Unfinished{
+		Pages:  frontier.Len() + len(inFlight),
+		Images: len(leftImages),
+	})
+	prog.setQueues(frontier.Len(), len(inFlight), len(leftImages))
+	prog.close()
+	saveReport(crawls, crawlID, report, *statsPath)
+	report.Print(os.Stdout)
+
+	slog.Info("crawl finished", "outcome", outcome)
+}
+
+// unfinishedImages merges the undispatched backlog with images the workers
+// never finished.
+func unfinishedImages(backlog []imageJob, tracker *imageTracker) []imageJob {
+	seen := make(map[string]struct{}, len(backlog))
+	out := make([]imageJob, 0, len(backlog))
+	for _, j := range append(backlog, tracker.unfinished()...) {
+		if _, ok := seen[j.Ref.URL]; ok {
+			continue
+		}
+		seen[j.Ref.URL] = struct{}{}
+		out = append(out, j)
+	}
+	return out
+}
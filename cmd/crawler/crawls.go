@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/storage"
+)
+
+const crawlsUsage = `usage: crawler crawls <command> [flags]
+
+commands:
+  list     stored crawls, newest first (--name, --seed, --status filter)
+  delete   delete crawls by id with their pages, images and image files`
+
+// startCrawlRecord stores a new running crawl, or marks the crawl resumeID
+// as running again, and returns its id. If the record cannot be written
+// the crawl still runs and stores its pages and images without a crawl id.
+func startCrawlRecord(crawls *storage.CrawlRepository, opts *crawlOptions, resumeID int64) int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if resumeID != 0 {
+		err := crawls.ResumeCrawl(ctx, resumeID)
+		if err == nil {
+			slog.Info("resuming crawl record", "crawl_id", resumeID)
+			return resumeID
+		}
+		slog.Warn("cannot resume crawl record, starting a new one", "crawl_id", resumeID, "err", err)
+	}
+
+	now := time.Now()
+	name := opts.name
+	if name == "" {
+		name = crawler.HostOf(opts.startURL) + " " + now.Format("2006-01-02 15:04")
+	}
+	id, err := crawls.StartCrawl(ctx, name, opts.startURL, opts.record(), now)
+	if err != nil {
+		slog.Error("db insert failed", "table", "crawls", "err", err)
+		return 0
+	}
+	slog.Info("crawl record started", "crawl_id", id, "name", name)
+	return id
+}
+
+// finishCrawlRecord stores the outcome, and the statistics report if there
+// is one, of the crawl id.
+func finishCrawlRecord(crawls *storage.CrawlRepository, id int64, outcome string, stats []byte) {
+	if id == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := crawls.FinishCrawl(ctx, id, outcome, time.Now(), stats); err != nil {
+		slog.Error("db update failed", "table", "crawls", "crawl_id", id, "err", err)
+		return
+	}
+	slog.Info("crawl record finished", "crawl_id", id, "status", outcome)
+}
+
+// runCrawls lists and deletes stored crawls.
+func runCrawls(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, crawlsUsage)
+		os.Exit(2)
+	}
+	cmd, args := args[0], args[1:]
+	ctx := context.Background()
+
+	switch cmd {
+	case "list":
+		fs := flag.NewFlagSet("crawls list", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		var f storage.CrawlFilter
+		fs.StringVar(&f.Name, "name", "", "Only crawls whose name contains this")
+		fs.StringVar(&f.Seed, "seed", "", "Only crawls whose seed URL contains this")
+		fs.StringVar(&f.Status, "status", "", "Only crawls with this status: running, complete, timeout, interrupted or failed")
+		fs.Parse(args)
+
+		crawls := storage.NewCrawlRepository(openStore(loadConfig(*cfgPath)))
+		list, err := crawls.ListCrawls(ctx, f)
+		if err != nil {
+			logging.Fatal("crawls list", "err", err)
+		}
+		printCrawls(list)
+
+	case "delete":
+		fs := flag.NewFlagSet("crawls delete", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		keepFiles := fs.Bool("keep-files", false, "Keep the downloaded image and thumbnail files")
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			logging.Fatal("usage: crawler crawls delete [--keep-files] ID...")
+		}
+		ids := make([]int64, 0, fs.NArg())
+		for _, a := range fs.Args() {
+			id, err := strconv.ParseInt(a, 10, 64)
+			if err != nil || id <= 0 {
+				logging.Fatal("invalid crawl id", "id", a)
+			}
+			ids = append(ids, id)
+		}
+
+		crawls := storage.NewCrawlRepository(openStore(loadConfig(*cfgPath)))
+		failed := false
+		for _, id := range ids {
+			files, err := crawls.DeleteCrawl(ctx, id)
+			if err != nil {
+				if errors.Is(err, storage.ErrNoCrawl) {
+					slog.Error("crawls delete", "crawl_id", id, "err", err)
+				} else {
+					slog.Error("db delete failed", "table", "crawls", "crawl_id", id, "err", err)
+				}
+				failed = true
+				continue
+			}
+			removed := 0
+			if !*keepFiles {
+				removed = removeFiles(files)
+			}
+			fmt.Printf("deleted crawl %d (%d files removed)\n", id, removed)
+		}
+		if failed {
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, crawlsUsage)
+		os.Exit(2)
+	}
+}
+
+// removeFiles deletes image files no longer referenced by any crawl and
+// returns how many were removed.
+func removeFiles(paths []string) int {
+	n := 0
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("remove image file", "path", p, "err", err)
+			}
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func printCrawls(list []storage.CrawlRecord) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tSTARTED\tDURATION\tPAGES\tIMAGES\tSEED")
+	for _, c := range list {
+		duration := "-"
+		if c.FinishedAt != nil {
+			duration = c.FinishedAt.Sub(c.StartedAt).Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			c.ID, c.Name, c.Status, c.StartedAt.Format("2006-01-02 15:04"), duration, c.Pages, c.Images, c.Seed)
+	}
+	tw.Flush()
+	fmt.Println(len(list), "crawls")
+}
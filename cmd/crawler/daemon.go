@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"GoCrawler/internal/config"
+	"GoCrawler/internal/cron"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/stats"
+)
+
+// keepRuns is how many finished runs per crawl the status endpoint shows.
+const keepRuns = 10
+
+// runRecord is the result of one scheduled run, as kept in memory, served
+// by /status and appended to history.jsonl in the runs directory.
+type runRecord struct {
+	Name     string     `json:"name"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Status   string     `json:"status"` // running, or the crawl outcome
+	ExitCode int        `json:"exit_code"`
+	Pages    int        `json:"pages"`
+	Images   int        `json:"images"`
+	Log      string     `json:"log"`
+	Stats    string     `json:"stats"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// scheduledCrawl is one entry of daemon.crawls. The fields below sched are
+// guarded by daemon.mu.
+type scheduledCrawl struct {
+	config.Schedule
+	sched *cron.Schedule
+
+	state   string // idle, queued (waiting for a slot) or running
+	next    time.Time
+	skipped int // activations dropped because the previous run was still active
+	current *runRecord
+	runs    []runRecord // finished runs, oldest first
+}
+
+type daemon struct {
+	exe     string
+	cfgPath string
+	runsDir string
+	grace   time.Duration
+	slots   chan struct{}
+	started time.Time
+
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	crawls []*scheduledCrawl
+}
+
+// runDaemon runs the crawls listed in the config file's daemon section on
+// their cron schedules, each as a child crawler process, and serves their
+// status over HTTP until SIGINT/SIGTERM.
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	cfgPath := registerConfig(fs)
+	listen := fs.String("listen", "", "Address to serve the schedule status on (default: daemon.listen from the config, :9300)")
+	maxConcurrent := fs.Int("max-concurrent", 0, "Max crawls running at once (default: daemon.max_concurrent from the config, 1)")
+	runsDir := fs.String("runs-dir", "", "Directory for per-run logs, stats reports and history (default: daemon.runs_dir from the config, runs)")
+	grace := fs.Int("grace", 60, "Seconds running crawls get to stop after SIGINT/SIGTERM before they are killed")
+	var lopts logging.Options
+	lopts.Register(fs)
+	fs.Parse(args)
+	applyLogging(lopts)
+	cfg := loadConfig(*cfgPath)
+
+	dc := cfg.Daemon
+	if flagSet(fs, "listen") {
+		dc.Listen = *listen
+	}
+	if flagSet(fs, "max-concurrent") {
+		dc.MaxConcurrent = *maxConcurrent
+	}
+	if flagSet(fs, "runs-dir") {
+		dc.RunsDir = *runsDir
+	}
+	if len(dc.Crawls) == 0 {
+		logging.Fatal("no scheduled crawls: add them to daemon.crawls in the config file")
+	}
+	if dc.MaxConcurrent < 1 {
+		logging.Fatal("--max-concurrent must be at least 1", "max_concurrent", dc.MaxConcurrent)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		logging.Fatal("locate crawler binary", "err", err)
+	}
+	d := &daemon{
+		exe:     exe,
+		runsDir: dc.RunsDir,
+		grace:   time.Duration(*grace) * time.Second,
+		slots:   make(chan struct{}, dc.MaxConcurrent),
+		started: time.Now(),
+	}
+	if *cfgPath != "" {
+		// Children run with the same config; $GOCRAWLER_CONFIG is inherited.
+		if d.cfgPath, err = filepath.Abs(*cfgPath); err != nil {
+			logging.Fatal("resolve config path", "path", *cfgPath, "err", err)
+		}
+	}
+	for _, s := range dc.Crawls {
+		sched, _ := cron.Parse(s.Cron) // checked by config.Validate
+		d.crawls = append(d.crawls, &scheduledCrawl{Schedule: s, sched: sched, state: "idle"})
+	}
+	if err := os.MkdirAll(d.runsDir, 0o755); err != nil {
+		logging.Fatal("create runs dir", "path", d.runsDir, "err", err)
+	}
+	d.loadHistory()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", d.handleStatus)
+	srv := &http.Server{Addr: dc.Listen, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("daemon listen", "addr", dc.Listen, "err", err)
+		}
+	}()
+	slog.Info("daemon start", "crawls", len(d.crawls), "max_concurrent", dc.MaxConcurrent, "runs_dir", d.runsDir, "listen", dc.Listen)
+
+	d.schedule(ctx)
+
+	// Let a second signal terminate the daemon the default way.
+	stop()
+	slog.Info("daemon stopping, waiting for running crawls", "grace", d.grace)
+	d.wg.Wait()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	srv.Shutdown(shutdownCtx)
+	slog.Info("daemon stopped")
+}
+
+// schedule starts every crawl that is due and sleeps until the next
+// activation, until ctx is done.
+func (d *daemon) schedule(ctx context.Context) {
+	d.mu.Lock()
+	now := time.Now()
+	for _, c := range d.crawls {
+		c.next = c.sched.Next(now)
+		slog.Info("crawl scheduled", "name", c.Name, "cron", c.Cron, "next", c.next)
+	}
+	d.mu.Unlock()
+
+	for {
+		d.mu.Lock()
+		now := time.Now()
+		var wake time.Time
+		for _, c := range d.crawls {
+			if !c.next.IsZero() && !c.next.After(now) {
+				d.trigger(ctx, c)
+				c.next = c.sched.Next(now)
+			}
+			if !c.next.IsZero() && (wake.IsZero() || c.next.Before(wake)) {
+				wake = c.next
+			}
+		}
+		d.mu.Unlock()
+
+		if wake.IsZero() {
+			<-ctx.Done()
+			return
+		}
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// trigger starts a run of c unless the previous one is still queued or
+// running. d.mu must be held.
+func (d *daemon) trigger(ctx context.Context, c *scheduledCrawl) {
+	if c.state != "idle" {
+		c.skipped++
+		slog.Warn("previous run still active, skipping", "name", c.Name, "state", c.state)
+		return
+	}
+	c.state = "queued"
+	d.wg.Add(1)
+	go d.run(ctx, c)
+}
+
+// run waits for a free slot, then runs c as a child crawler process and
+// records the result.
+func (d *daemon) run(ctx context.Context, c *scheduledCrawl) {
+	defer d.wg.Done()
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		d.mu.Lock()
+		c.state = "idle"
+		d.mu.Unlock()
+		return
+	}
+	defer func() { <-d.slots }()
+
+	started := time.Now()
+	dir := filepath.Join(d.runsDir, c.Name)
+	stamp := started.Format("20060102-150405")
+	rec := runRecord{
+		Name:    c.Name,
+		Started: started,
+		Status:  "running",
+		Log:     filepath.Join(dir, stamp+".log"),
+		Stats:   filepath.Join(dir, stamp+".json"),
+	}
+	d.mu.Lock()
+	c.state = "running"
+	c.current = &rec
+	d.mu.Unlock()
+
+	d.exec(ctx, c, &rec)
+
+	now := time.Now()
+	rec.Finished = &now
+	d.mu.Lock()
+	c.state = "idle"
+	c.current = nil
+	c.runs = append(c.runs, rec)
+	if len(c.runs) > keepRuns {
+		c.runs = c.runs[len(c.runs)-keepRuns:]
+	}
+	d.appendHistory(rec)
+	d.mu.Unlock()
+
+	slog.Info("scheduled crawl finished", "name", c.Name, "status", rec.Status, "exit_code", rec.ExitCode,
+		"pages", rec.Pages, "images", rec.Images, "elapsed", now.Sub(started).Round(time.Second), "log", rec.Log)
+}
+
+// exec runs the crawler for c with its output in rec.Log and fills in the
+// result from the stats report. When ctx is done the child gets SIGINT and
+// d.grace to shut down before it is killed.
+func (d *daemon) exec(ctx context.Context, c *scheduledCrawl, rec *runRecord) {
+	fail := func(err error) {
+		rec.Status = "failed"
+		rec.Error = err.Error()
+		slog.Error("scheduled crawl failed", "name", c.Name, "err", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(rec.Log), 0o755); err != nil {
+		fail(err)
+		return
+	}
+	logFile, err := os.Create(rec.Log)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer logFile.Close()
+
+	args := []string{"--name", c.Name, "--stats", rec.Stats}
+	if d.cfgPath != "" {
+		args = append(args, "--config", d.cfgPath)
+	}
+	args = append(args, c.Args...)
+
+	cmd := exec.CommandContext(ctx, d.exe, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = d.grace
+	detach(cmd)
+
+	slog.Info("scheduled crawl start", "name", c.Name, "log", rec.Log)
+	err = cmd.Run()
+	if cmd.ProcessState != nil {
+		rec.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	data, readErr := os.ReadFile(rec.Stats)
+	if readErr != nil {
+		// The crawler exits before writing a report only when it could
+		// not start (bad flags, database down); the reason is in the log.
+		if err == nil {
+			err = readErr
+		}
+		rec.Stats = ""
+		fail(fmt.Errorf("%w (see %s)", err, rec.Log))
+		return
+	}
+	var report stats.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		fail(fmt.Errorf("read stats report %s: %w", rec.Stats, err))
+		return
+	}
+	rec.Status = report.Outcome
+	rec.Pages = report.Pages.OK
+	rec.Images = report.Images.OK
+}
+
+// appendHistory adds rec to history.jsonl. d.mu must be held.
+func (d *daemon) appendHistory(rec runRecord) {
+	path := filepath.Join(d.runsDir, "history.jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		slog.Error("write run history", "path", path, "err", err)
+		return
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(rec); err != nil {
+		slog.Error("write run history", "path", path, "err", err)
+	}
+}
+
+// loadHistory restores the recent runs of each scheduled crawl from
+// history.jsonl, so the status survives a restart.
+func (d *daemon) loadHistory() {
+	path := filepath.Join(d.runsDir, "history.jsonl")
+	f, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("read run history", "path", path, "err", err)
+		}
+		return
+	}
+	defer f.Close()
+
+	byName := make(map[string]*scheduledCrawl, len(d.crawls))
+	for _, c := range d.crawls {
+		byName[c.Name] = c
+	}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var rec runRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		if c := byName[rec.Name]; c != nil {
+			c.runs = append(c.runs, rec)
+			if len(c.runs) > keepRuns {
+				c.runs = c.runs[1:]
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		slog.Warn("read run history", "path", path, "err", err)
+	}
+}
+
+type daemonStatus struct {
+	Started       time.Time     `json:"started"`
+	MaxConcurrent int           `json:"max_concurrent"`
+	Running       int           `json:"running"`
+	Crawls        []crawlStatus `json:"crawls"`
+}
+
+type crawlStatus struct {
+	Name    string      `json:"name"`
+	Cron    string      `json:"cron"`
+	Args    []string    `json:"args"`
+	State   string      `json:"state"`
+	NextRun *time.Time  `json:"next_run,omitempty"`
+	Skipped int         `json:"skipped"`
+	Current *runRecord  `json:"current,omitempty"`
+	LastRun *runRecord  `json:"last_run,omitempty"`
+	Runs    []runRecord `json:"runs"` // newest first
+}
+
+// handleStatus serves the schedule, the running crawls and the recent
+// results of each scheduled crawl as JSON.
+func (d *daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	st := daemonStatus{Started: d.started, MaxConcurrent: cap(d.slots), Crawls: make([]crawlStatus, 0, len(d.crawls))}
+	for _, c := range d.crawls {
+		cs := crawlStatus{Name: c.Name, Cron: c.Cron, Args: c.Args, State: c.state, Skipped: c.skipped, Runs: []runRecord{}}
+		if !c.next.IsZero() {
+			next := c.next
+			cs.NextRun = &next
+		}
+		if c.current != nil {
+			cur := *c.current
+			cs.Current = &cur
+			st.Running++
+		}
+		for i := len(c.runs) - 1; i >= 0; i-- {
+			cs.Runs = append(cs.Runs, c.runs[i])
+		}
+		if len(cs.Runs) > 0 {
+			cs.LastRun = &cs.Runs[0]
+		}
+		st.Crawls = append(st.Crawls, cs)
+	}
+	d.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(st)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"GoCrawler/internal/export"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/storage"
+)
+
+// runExport writes the pages with extracted fields, and the images found
+// on them, as CSV or JSON.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	cfgPath := registerConfig(fs)
+	var f storage.FieldFilter
+	fs.Int64Var(&f.CrawlID, "crawl", 0, "Only pages (and images) of this crawl id")
+	fs.StringVar(&f.Name, "field", "", "Only pages with this field")
+	fs.StringVar(&f.Value, "value", "", "Only pages with a field value (of --field, if set) containing this")
+	format := fs.String("format", "csv", "Output format: csv or json")
+	out := fs.String("out", "", "Write to this file instead of stdout")
+	fs.Parse(args)
+	if *format != "csv" && *format != "json" {
+		logging.Fatal("--format must be csv or json", "format", *format)
+	}
+
+	pages := storage.NewPageRepository(openStore(loadConfig(*cfgPath)))
+	list, err := pages.ExportPages(context.Background(), f)
+	if err != nil {
+		logging.Fatal("export", "err", err)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			logging.Fatal("export", "err", err)
+		}
+	}
+	bw := bufio.NewWriter(w)
+	if err := export.Write(bw, *format, list); err != nil {
+		logging.Fatal("export", "err", err)
+	}
+	if err := bw.Flush(); err != nil {
+		logging.Fatal("export", "err", err)
+	}
+	if *out != "" {
+		if err := w.Close(); err != nil {
+			logging.Fatal("export", "err", err)
+		}
+		slog.Info("exported pages", "pages", len(list), "path", *out)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"GoCrawler/internal/config"
+	"GoCrawler/internal/crawler"
+)
+
+// crawlOptions are the flags deciding what gets crawled: seed, scope, traps
+// and budgets. They are used by the standalone crawler and the coordinator.
+type crawlOptions struct {
+	name           string
+	startURL       string
+	maxDepth       int
+	followExternal bool
+	useJS          bool
+	links          crawler.LinkOptions
+	lang           string
+	languages      []string // lang, parsed by validate
+	hreflang       string   // "" for all variants once validated
+
+	maxPages    int
+	maxImages   int
+	maxBytes    int64
+	maxDuration int
+	maxPerHost  int
+
+	traps crawler.TrapRules
+}
+
+func (o *crawlOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.startURL, "url", "", "Start URL to crawl (required)")
+	fs.StringVar(&o.name, "name", "", "Name of the crawl record (default: seed host and start time)")
+	fs.IntVar(&o.maxDepth, "depth", 2, "Max depth (0 = only seed page)")
+	fs.BoolVar(&o.followExternal, "external", false, "Follow external page links")
+	fs.BoolVar(&o.useJS, "js", false, "Use headless browser (chromedp) to render JS pages")
+	fs.StringVar(&o.hreflang, "hreflang", "all", `Of pages with <link rel="alternate" hreflang> variants, crawl only the one for this language, or "all"`)
+	fs.StringVar(&o.lang, "lang", "", "Only keep and follow pages in these languages, comma-separated ISO 639-1 codes (pages of unknown language are kept)")
+
+	fs.BoolVar(&o.links.Area, "link-area", true, "Follow <area href> links")
+	fs.BoolVar(&o.links.Frames, "link-frames", true, "Follow <iframe>/<frame> src links")
+	fs.BoolVar(&o.links.LinkRel, "link-rel", true, `Follow <link rel="next|prev|alternate"> links`)
+	fs.BoolVar(&o.links.MetaRefresh, "link-meta-refresh", true, `Follow <meta http-equiv="refresh"> targets`)
+	fs.BoolVar(&o.links.Forms, "link-forms", false, "Follow <form method=get> actions")
+
+	fs.IntVar(&o.maxPages, "max-pages", 0, "Stop fetching pages after this many (0 = unlimited)")
+	fs.IntVar(&o.maxImages, "max-images", 0, "Stop processing images after this many (0 = unlimited)")
+	fs.Int64Var(&o.maxBytes, "max-bytes", 0, "Drain the crawl after downloading this many bytes (0 = unlimited)")
+	fs.IntVar(&o.maxDuration, "max-duration", 0, "Drain the crawl after this many seconds (0 = unlimited; --timeout still applies)")
+	fs.IntVar(&o.maxPerHost, "max-pages-per-host", 0, "Max pages fetched from a single host (0 = unlimited)")
+
+	d := crawler.DefaultTrapRules()
+	fs.IntVar(&o.traps.MaxURLLength, "trap-max-url-length", d.MaxURLLength, "Reject URLs longer than this (0 = off)")
+	fs.IntVar(&o.traps.MaxPathDepth, "trap-max-path-depth", d.MaxPathDepth, "Reject URLs with more path segments than this (0 = off)")
+	fs.IntVar(&o.traps.MaxRepeats, "trap-max-repeats", d.MaxRepeats, "Reject URLs repeating a path segment more than this (0 = off)")
+	fs.IntVar(&o.traps.MaxQueryVariants, "trap-max-query-variants", d.MaxQueryVariants, "Max distinct query strings per path (0 = off)")
+	fs.IntVar(&o.traps.MaxTemplateURLs, "trap-max-template-urls", d.MaxTemplateURLs, "Max distinct URLs per path template (0 = off)")
+}
+
+// validate checks the options that can be invalid once parsed.
+func (o *crawlOptions) validate() error {
+	langs, err := crawler.ParseLanguages(o.lang)
+	if err != nil {
+		return fmt.Errorf("--lang: %w", err)
+	}
+	o.languages = langs
+	if o.hreflang, err = crawler.ParseHreflang(o.hreflang); err != nil {
+		return fmt.Errorf("--hreflang: %w", err)
+	}
+	return nil
+}
+
+// attrs are the options as log attributes.
+func (o *crawlOptions) attrs() []any {
+	return []any{
+		"name", o.name,
+		"url", o.startURL,
+		"depth", o.maxDepth,
+		"external", o.followExternal,
+		"js", o.useJS,
+		"lang", o.lang,
+		"hreflang", o.hreflang,
+		slog.Group("links", "area", o.links.Area, "frames", o.links.Frames, "rel", o.links.LinkRel, "meta_refresh", o.links.MetaRefresh, "forms", o.links.Forms),
+		slog.Group("traps", "url_length", o.traps.MaxURLLength, "path_depth", o.traps.MaxPathDepth, "repeats", o.traps.MaxRepeats, "query_variants", o.traps.MaxQueryVariants, "template_urls", o.traps.MaxTemplateURLs),
+		slog.Group("budget", "pages", o.maxPages, "images", o.maxImages, "bytes", o.maxBytes, "duration", o.maxDuration, "per_host", o.maxPerHost),
+	}
+}
+
+// record is the options as stored with the crawl record, in JSON.
+func (o *crawlOptions) record() []byte {
+	data, _ := json.Marshal(map[string]any{
+		"url":      o.startURL,
+		"depth":    o.maxDepth,
+		"external": o.followExternal,
+		"js":       o.useJS,
+		"lang":     o.languages,
+		"hreflang": o.hreflang,
+		"links":    map[string]bool{"area": o.links.Area, "frames": o.links.Frames, "rel": o.links.LinkRel, "meta_refresh": o.links.MetaRefresh, "forms": o.links.Forms},
+		"traps":    map[string]int{"url_length": o.traps.MaxURLLength, "path_depth": o.traps.MaxPathDepth, "repeats": o.traps.MaxRepeats, "query_variants": o.traps.MaxQueryVariants, "template_urls": o.traps.MaxTemplateURLs},
+		"budget":   map[string]int64{"pages": int64(o.maxPages), "images": int64(o.maxImages), "bytes": o.maxBytes, "duration": int64(o.maxDuration), "per_host": int64(o.maxPerHost)},
+	})
+	return data
+}
+
+func (o *crawlOptions) budget() *crawler.Budget {
+	b := &crawler.Budget{
+		MaxPages:        o.maxPages,
+		MaxImages:       o.maxImages,
+		MaxBytes:        o.maxBytes,
+		MaxDuration:     time.Duration(o.maxDuration) * time.Second,
+		MaxPagesPerHost: o.maxPerHost,
+	}
+	b.Start()
+	return b
+}
+
+func (o *crawlOptions) frontier(budget *crawler.Budget) *crawler.Frontier {
+	tmpl := crawler.CrawlJob{
+		FollowExternal: o.followExternal,
+		UseJS:          o.useJS,
+		LinkSources:    o.links,
+		Languages:      o.languages,
+		Hreflang:       o.hreflang,
+	}
+	return crawler.NewFrontier(o.startURL, tmpl, crawler.NewTrapDetector(o.traps), budget)
+}
+
+// workerOptions size the local page and image worker pools.
+type workerOptions struct {
+	workers    int
+	imgWorkers int
+	imgTimeout int
+	maxG       int
+
+	adaptive    bool
+	hostMin     int
+	hostMax     int
+	hostInitial int
+
+	warcDir    string
+	warcPrefix string
+	warcMaxMB  int
+	replay     string
+
+	metricsAddr string
+	processors  string
+
+	dirs config.Dirs
+}
+
+func (o *workerOptions) register(fs *flag.FlagSet) {
+	fs.IntVar(&o.workers, "workers", 10, "Number of crawler workers")
+	fs.IntVar(&o.imgWorkers, "img-workers", 4, "Number of image processing workers")
+	fs.IntVar(&o.imgTimeout, "img-timeout", 20, "Per-image processing timeout in seconds")
+	fs.IntVar(&o.maxG, "max-goroutines", 200, "Hard cap for total goroutines (crawl + image workers)")
+	fs.BoolVar(&o.adaptive, "adaptive", false, "Adapt per-host concurrency to the host's latency and errors (AIMD)")
+	fs.IntVar(&o.hostMin, "host-min", 1, "With --adaptive, the lowest per-host concurrency")
+	fs.IntVar(&o.hostMax, "host-max", 0, "With --adaptive, the highest per-host concurrency (0: --workers)")
+	fs.IntVar(&o.hostInitial, "host-initial", 2, "With --adaptive, the per-host concurrency to start at")
+
+	fs.StringVar(&o.warcDir, "warc-dir", "", "Archive every HTTP request/response as gzip'd WARC files in this directory")
+	fs.StringVar(&o.warcPrefix, "warc-prefix", "gocrawler", "WARC file name prefix")
+	fs.IntVar(&o.warcMaxMB, "warc-max-mb", 1024, "Start a new WARC file after this many megabytes")
+	fs.StringVar(&o.replay, "replay", "", "Serve all fetches from these WARC files/directories (comma-separated) instead of the network")
+
+	fs.StringVar(&o.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9100 (empty disables)")
+	fs.StringVar(&o.processors, "processors", "", "Comma-separated page processors to run on every page, in order (available: opengraph)")
+}
+
+// fromConfig takes the worker settings the command line left unset from
+// cfg.
+func (o *workerOptions) fromConfig(cfg *config.Config, fs *flag.FlagSet) {
+	if !flagSet(fs, "workers") {
+		o.workers = cfg.Workers.Pages
+	}
+	if !flagSet(fs, "img-workers") {
+		o.imgWorkers = cfg.Workers.Images
+	}
+	if !flagSet(fs, "img-timeout") {
+		o.imgTimeout = int(cfg.Workers.ImageTimeout.Std().Seconds())
+	}
+	if !flagSet(fs, "max-goroutines") {
+		o.maxG = cfg.Workers.MaxGoroutines
+	}
+	hc := cfg.Workers.HostConcurrency
+	if !flagSet(fs, "adaptive") {
+		o.adaptive = hc.Adaptive
+	}
+	if !flagSet(fs, "host-min") {
+		o.hostMin = hc.Min
+	}
+	if !flagSet(fs, "host-max") {
+		o.hostMax = hc.Max
+	}
+	if !flagSet(fs, "host-initial") {
+		o.hostInitial = hc.Initial
+	}
+	o.dirs = cfg.Dirs
+}
+
+// attrs are the options as log attributes.
+func (o *workerOptions) attrs() []any {
+	attrs := []any{
+		"workers", o.workers,
+		"img_workers", o.imgWorkers,
+		"img_timeout", o.imgTimeout,
+		"max_goroutines", o.maxG,
+		"image_dir", o.dirs.Images,
+		"thumbnail_dir", o.dirs.Thumbnails,
+	}
+	if o.adaptive {
+		attrs = append(attrs, slog.Group("host_concurrency", "min", o.hostMin, "max", o.hostMax, "initial", o.hostInitial))
+	}
+	if o.replay != "" {
+		attrs = append(attrs, "replay", o.replay)
+	}
+	if o.warcDir != "" {
+		attrs = append(attrs, slog.Group("warc", "dir", o.warcDir, "prefix", o.warcPrefix, "max_mb", o.warcMaxMB))
+	}
+	if o.metricsAddr != "" {
+		attrs = append(attrs, "metrics_addr", o.metricsAddr)
+	}
+	if o.processors != "" {
+		attrs = append(attrs, "processors", o.processors)
+	}
+	return attrs
+}
+
+func (o *workerOptions) validate() error {
+	if (o.workers + o.imgWorkers + 10) > o.maxG {
+		return fmt.Errorf("too many goroutines requested: workers=%d img-workers=%d max-goroutines=%d",
+			o.workers, o.imgWorkers, o.maxG)
+	}
+	if _, err := newCrawler(nil, o.processors); err != nil {
+		return fmt.Errorf("--processors: %w", err)
+	}
+	if o.adaptive {
+		if o.hostMin < 1 || o.hostInitial < o.hostMin || o.hostMax != 0 && o.hostInitial > o.hostMax {
+			return fmt.Errorf("want 1 <= host-min <= host-initial <= host-max, got host-min=%d host-initial=%d host-max=%d",
+				o.hostMin, o.hostInitial, o.hostMax)
+		}
+		if o.hostMin > o.workers {
+			return fmt.Errorf("host-min=%d is above workers=%d", o.hostMin, o.workers)
+		}
+	}
+	return nil
+}
+
+// validateBrowser checks the options for a crawl that renders pages in a
+// browser. Browser fetches bypass fetch.Transport, so they cannot be
+// recorded.
+func (o *workerOptions) validateBrowser() error {
+	if o.warcDir != "" {
+		return errors.New("--warc-dir cannot record pages rendered in a browser (--js or host js rules)")
+	}
+	return nil
+}
+
+// hostLimiter returns the per-host concurrency limiter for --adaptive, or
+// nil without it. No host goes above --workers.
+func (o *workerOptions) hostLimiter() *crawler.HostLimiter {
+	if !o.adaptive {
+		return nil
+	}
+	hi := o.hostMax
+	if hi == 0 {
+		hi = o.workers
+	}
+	return crawler.NewHostLimiter(o.hostMin, min(hi, o.workers), o.hostInitial)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+	"GoCrawler/internal/storage"
+)
+
+// graphWriter stores fetched pages with their outgoing links and extracted
+// fields, and fetch failures, off the dispatch loop. close flushes
+// everything queued.
+type graphWriter struct {
+	repo    *storage.PageRepository
+	items   chan graphItem
+	wg      sync.WaitGroup
+	crawlID atomic.Int64 // crawl the stored pages belong to; 0 for none
+}
+
+// graphItem is one queued write: a crawled page, a failed fetch, or the URL
+// of an image that was fetched fine.
+type graphItem struct {
+	result  *crawler.CrawlResult
+	failure *storage.Failure
+	fetched string
+}
+
+// dbWriteTimeout bounds one database write. Writes do not use the crawl
+// context, so a timeout or Ctrl-C still flushes them.
+const dbWriteTimeout = 30 * time.Second
+
+func startGraphWriter(repo *storage.PageRepository, crawlID int64) *graphWriter {
+	g := &graphWriter{repo: repo, items: make(chan graphItem, 256)}
+	g.crawlID.Store(crawlID)
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		for item := range g.items {
+			g.save(item)
+		}
+	}()
+	return g
+}
+
+func (g *graphWriter) add(result crawler.CrawlResult) { g.items <- graphItem{result: &result} }
+
+// fail records a page or image ("page", "image") that could not be fetched.
+func (g *graphWriter) fail(kind, url string, err error) {
+	class, code := fetch.Classify(err)
+	g.items <- graphItem{failure: &storage.Failure{
+		URL:        url,
+		Kind:       kind,
+		StatusCode: code,
+		ErrorClass: class,
+		Error:      err.Error(),
+	}}
+}
+
+// ok clears any failure stored for url by an earlier crawl.
+func (g *graphWriter) ok(url string) { g.items <- graphItem{fetched: url} }
+
+func (g *graphWriter) close() {
+	close(g.items)
+	g.wg.Wait()
+}
+
+func (g *graphWriter) save(item graphItem) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbWriteTimeout)
+	defer cancel()
+
+	switch {
+	case item.failure != nil:
+		defer observeDB("failures", time.Now())
+		if err := g.repo.RecordFailure(ctx, *item.failure); err != nil {
+			slog.Error("db insert failed", "table", "failures", "url", item.failure.URL, "err", err)
+		}
+	case item.fetched != "":
+		defer observeDB("failures", time.Now())
+		if err := g.repo.ClearFailure(ctx, item.fetched); err != nil {
+			slog.Error("db delete failed", "table", "failures", "url", item.fetched, "err", err)
+		}
+	case item.result != nil:
+		g.savePage(ctx, item.result)
+	}
+}
+
+func (g *graphWriter) savePage(ctx context.Context, result *crawler.CrawlResult) {
+	start := time.Now()
+	id, err := g.repo.SavePage(ctx, g.crawlID.Load(), storage.Page{
+		URL:         result.URL,
+		Title:       result.Title,
+		Description: result.Description,
+		Lang:        result.Lang,
+		Text:        result.Text,
+		Hreflang:    selfHreflang(result),
+		Variant:     result.Variant,
+		Depth:       result.Distance,
+	})
+	observeDB("pages", start)
+	if err != nil {
+		slog.Error("db insert failed", "table", "pages", "url", result.URL, "err", err)
+		return
+	}
+	if err := g.repo.ClearFailure(ctx, result.URL); err != nil {
+		slog.Error("db delete failed", "table", "failures", "url", result.URL, "err", err)
+	}
+
+	// Image references are stored as edges too, so a broken image can be
+	// traced back to the pages embedding it.
+	links := make([]storage.PageLink, 0, len(result.Links)+len(result.Images))
+	for _, l := range result.Links {
+		links = append(links, storage.PageLink{
+			TargetURL:  l.URL,
+			AnchorText: l.Text,
+			Rel:        l.Rel,
+			Element:    l.Source,
+			Depth:      result.Distance + 1,
+		})
+	}
+	for _, img := range crawler.UniqueImages(result.Images) {
+		links = append(links, storage.PageLink{
+			TargetURL:  img.URL,
+			AnchorText: img.Alt,
+			Element:    "img",
+			Depth:      result.Distance + 1,
+		})
+	}
+	start = time.Now()
+	err = g.repo.InsertLinks(ctx, id, links)
+	observeDB("links", start)
+	if err != nil {
+		slog.Error("db insert failed", "table", "links", "url", result.URL, "err", err)
+	}
+
+	fields := make([]storage.PageField, len(result.Records))
+	for i, r := range result.Records {
+		fields[i] = storage.PageField{Source: r.Processor, Name: r.Key, Value: r.Value}
+	}
+	start = time.Now()
+	err = g.repo.SavePageFields(ctx, id, fields)
+	observeDB("page_fields", start)
+	if err != nil {
+		slog.Error("db insert failed", "table", "page_fields", "url", result.URL, "err", err)
+	}
+}
+
+// selfHreflang is the hreflang a page declares for itself among its
+// variants.
+func selfHreflang(result *crawler.CrawlResult) string {
+	for _, a := range result.Alternates {
+		if a.URL == result.URL {
+			return a.Lang
+		}
+	}
+	return ""
+}
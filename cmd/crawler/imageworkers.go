@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"GoCrawler/internal/config"
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/images"
+	"GoCrawler/internal/storage"
+)
+
+// imageJob is an image queued for download along with the page it was found on.
+type imageJob struct {
+	Ref     crawler.ImageRef
+	PageURL string
+	CrawlID int64  `json:",omitempty"`
+	TaskID  string `json:",omitempty"` // the coordinator's ImageTask, on a node
+}
+
+func (j imageJob) annotate(meta *images.ImageMetadata) {
+	meta.PageURL = j.PageURL
+	meta.AltText = j.Ref.Alt
+	meta.Title = j.Ref.Title
+	meta.Caption = j.Ref.Figcaption
+	meta.Heading = j.Ref.Heading
+	meta.PageTitle = j.Ref.PageTitle
+	meta.SrcsetDescriptor = j.Ref.Descriptor
+	meta.Sizes = j.Ref.Sizes
+}
+
+// startImageWorkers downloads, thumbnails and stores every image sent on the
+// returned channel. Close the channel and wait on the WaitGroup to drain.
+// onDone, if set, is called after every attempted job with the downloaded
+// metadata (nil on failure) and the error, if any.
+func startImageWorkers(ctx context.Context, n int, timeout time.Duration, dirs config.Dirs, repo *storage.ImageRepository, onDone func(imageJob, *images.ImageMetadata, error)) (chan imageJob, *sync.WaitGroup) {
+	imageJobs := make(chan imageJob, 256)
+	var imgWG sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		imgWG.Add(1)
+		go func(id int) {
+			defer imgWG.Done()
+			slog.Debug("image worker start", "worker_id", id)
+
+			for {
+				select {
+				case <-ctx.Done():
+					slog.Debug("image worker exit", "worker_id", id, "err", ctx.Err())
+					return
+
+				case job, ok := <-imageJobs:
+					if !ok {
+						slog.Debug("image worker exit", "worker_id", id)
+						return
+					}
+					if ctx.Err() != nil {
+						slog.Debug("image worker exit", "worker_id", id, "err", ctx.Err())
+						return
+					}
+
+					imgURL := job.Ref.URL
+					slog.Debug("image start", "worker_id", id, "url", imgURL)
+
+					start := time.Now()
+					imgCtx, cancel := context.WithTimeout(ctx, timeout)
+					meta, err := images.ProcessImage(imgCtx, imgURL, dirs.Images, dirs.Thumbnails)
+					cancel()
+					elapsed := time.Since(start)
+
+					if err != nil {
+						if ctx.Err() == nil {
+							slog.Warn("image failed", "worker_id", id, "url", imgURL, "page", job.PageURL, "duration", elapsed, "err", err)
+							observeImage(0, elapsed, err)
+						}
+						if onDone != nil {
+							onDone(job, nil, err)
+						}
+						continue
+					}
+					if meta == nil {
+						slog.Warn("image skipped: no metadata", "worker_id", id, "url", imgURL)
+						continue
+					}
+					job.annotate(meta)
+					observeImage(meta.Size, elapsed, nil)
+
+					// the image is on disk: store it even if the crawl was
+					// canceled meanwhile
+					start = time.Now()
+					dbCtx, cancel := context.WithTimeout(context.Background(), dbWriteTimeout)
+					err = repo.InsertImage(dbCtx, job.CrawlID, meta)
+					cancel()
+					observeDB("images", start)
+					if onDone != nil {
+						onDone(job, meta, err)
+					}
+					if err != nil {
+						slog.Error("db insert failed", "table", "images", "url", imgURL, "err", err)
+						continue
+					}
+
+					slog.Info("image stored", "worker_id", id, "url", imgURL, "format", meta.Format, "bytes", meta.Size, "duration", elapsed)
+				}
+			}
+		}(i)
+	}
+
+	return imageJobs, &imgWG
+}
+
+// imageTracker remembers dispatched images until a worker finishes them, so
+// an interrupted crawl can checkpoint the ones still outstanding.
+type imageTracker struct {
+	mu      sync.Mutex
+	pending map[string]imageJob
+}
+
+func newImageTracker() *imageTracker {
+	return &imageTracker{pending: make(map[string]imageJob)}
+}
+
+func (t *imageTracker) dispatched(job imageJob) {
+	t.mu.Lock()
+	t.pending[job.Ref.URL] = job
+	t.mu.Unlock()
+}
+
+// done records a finished job. Jobs aborted by ctx cancellation stay pending.
+func (t *imageTracker) done(ctx context.Context, job imageJob, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	delete(t.pending, job.Ref.URL)
+}
+
+func (t *imageTracker) unfinished() []imageJob {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]imageJob, 0, len(t.pending))
+	for _, j := range t.pending {
+		out = append(out, j)
+	}
+	return out
+}
+
+// recordImage stores the outcome of an image job in the link graph: a fetch
+// failure as a broken image, a download as clearing any earlier failure.
+// Jobs aborted by ctx cancellation and DB insert errors are not recorded.
+func recordImage(ctx context.Context, graph *graphWriter, job imageJob, meta *images.ImageMetadata, err error) {
+	switch {
+	case meta != nil:
+		graph.ok(job.Ref.URL)
+	case err != nil && ctx.Err() == nil:
+		graph.fail("image", job.Ref.URL, err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/metrics"
+)
+
+// Crawler metrics, served on --metrics-addr. Gauges only move in the
+// standalone crawl; counters and histograms are updated by every mode.
+var (
+	registry = metrics.NewRegistry()
+
+	queueGauge    = registry.NewGauge("crawler_queue_length", "Pages waiting in the frontier.")
+	inFlightGauge = registry.NewGauge("crawler_in_flight_pages", "Pages dispatched to workers and not yet finished.")
+	backlogGauge  = registry.NewGauge("crawler_image_backlog", "Images waiting to be dispatched to image workers.")
+	hostLimit     = registry.NewGaugeVec("crawler_host_concurrency_limit", "Pages fetched at once per host with --adaptive, by host.", "host")
+
+	pagesTotal = registry.NewCounterVec("crawler_pages_total", "Pages fetched, by host and result (ok, error).", "host", "result")
+	pageBytes  = registry.NewCounterVec("crawler_page_bytes_total", "Page body bytes downloaded, by host.", "host")
+	pageFetch  = registry.NewHistogram("crawler_page_fetch_seconds", "Page fetch latency.", nil)
+
+	imagesTotal     = registry.NewCounterVec("crawler_images_total", "Image jobs finished, by result (ok, error).", "result")
+	imageBytesTotal = registry.NewCounter("crawler_image_bytes_total", "Image bytes downloaded.")
+	imageDuration   = registry.NewHistogram("crawler_image_processing_seconds", "Time to download, decode and thumbnail an image.", nil)
+
+	fetchErrors = registry.NewCounterVec("crawler_fetch_errors_total", "Failed fetches, by kind (page, image) and error class or HTTP status.", "kind", "class")
+	dbInsert    = registry.NewHistogramVec("crawler_db_insert_seconds", "Database write latency, by table.", nil, "table")
+)
+
+// serveMetrics exposes the registry on addr/metrics in the background. An
+// empty addr disables it.
+func serveMetrics(addr string, reg *metrics.Registry) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", reg.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("metrics listen", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("serving metrics", "addr", addr, "path", "/metrics")
+}
+
+// observePage counts a finished page job.
+func observePage(result crawler.CrawlResult) {
+	host := crawler.HostOf(result.URL)
+
+	pageFetch.Observe(result.Fetch.Seconds())
+	pageBytes.Add(float64(result.Bytes), host)
+	if result.Err != nil {
+		pagesTotal.Inc(host, "error")
+		fetchErrors.Inc("page", fetch.Label(result.Err))
+		return
+	}
+	pagesTotal.Inc(host, "ok")
+}
+
+// observeLimit exports the concurrency limit of the host of pageURL, if
+// limiter is set.
+func observeLimit(limiter *crawler.HostLimiter, pageURL string) {
+	if limiter == nil {
+		return
+	}
+	host := crawler.HostOf(pageURL)
+	hostLimit.Set(float64(limiter.Limit(host)), host)
+}
+
+// observeImage counts a finished image job that took elapsed.
+func observeImage(size int64, elapsed time.Duration, err error) {
+	imageDuration.Observe(elapsed.Seconds())
+	if err != nil {
+		imagesTotal.Inc("error")
+		fetchErrors.Inc("image", fetch.Label(err))
+		return
+	}
+	imagesTotal.Inc("ok")
+	imageBytesTotal.Add(float64(size))
+}
+
+// observeDB records the latency of a write to table that started at start.
+func observeDB(table string, start time.Time) {
+	dbInsert.Observe(time.Since(start).Seconds(), table)
+}
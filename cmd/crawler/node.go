@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"GoCrawler/internal/cluster"
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+	"GoCrawler/internal/images"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/storage"
+)
+
+// runNode leases page jobs from a coordinator, crawls them with the local
+// worker pool, reports the results and processes the images it is assigned.
+func runNode(args []string) {
+	fs := flag.NewFlagSet("node", flag.ExitOnError)
+	var wopts workerOptions
+	wopts.register(fs)
+	coordURL := fs.String("coordinator", "http://localhost:9090", "Coordinator base URL")
+	nodeID := fs.String("node-id", "", "Node name used in leases (default: hostname-pid)")
+	useJS := fs.Bool("js", false, "Start a headless browser for jobs that request JS rendering")
+	timeout := fs.Int("timeout", 3600, "Give up after this many seconds")
+	retries := fs.Int("retries", 30, "Consecutive coordinator errors before giving up")
+	cfgPath := registerConfig(fs)
+	var lopts logging.Options
+	lopts.Register(fs)
+	fs.Parse(args)
+	applyLogging(lopts)
+
+	cfg := loadConfig(*cfgPath)
+	wopts.fromConfig(cfg, fs)
+	applyFetchConfig(cfg)
+
+	if *nodeID == "" {
+		host, _ := os.Hostname()
+		*nodeID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	slog.Info("node start", append(wopts.attrs(), "node", *nodeID, "coordinator", *coordURL)...)
+
+	if err := wopts.validate(); err != nil {
+		logging.Fatal("invalid flags", "err", err)
+	}
+
+	if wopts.replay != "" {
+		*useJS = false
+	}
+
+	hostJS := wopts.replay == ""
+	useBrowser := *useJS || hostJS && cfg.WantsJS()
+	if useBrowser {
+		if err := wopts.validateBrowser(); err != nil {
+			logging.Fatal("invalid flags", "err", err)
+		}
+	}
+	baseCtx, browserCancel := browserContext(useBrowser)
+	defer browserCancel()
+
+	ctx, cancel := context.WithTimeout(baseCtx, time.Duration(*timeout)*time.Second)
+	defer cancel()
+
+	ensureDirs(wopts.dirs)
+	store := openStore(cfg)
+	repo := storage.NewImageRepository(store)
+	graph := startGraphWriter(storage.NewPageRepository(store), 0)
+
+	replayDone := startReplay(wopts)
+	defer replayDone()
+	closeWARC := startWARC(wopts)
+	defer closeWARC()
+	startHostRules(cfg, wopts)
+	serveMetrics(wopts.metricsAddr, registry)
+
+	pool := crawler.NewWorkerPool(ctx, wopts.workers, 200, 200)
+	pool.Limiter = wopts.hostLimiter()
+	pool.Start(processJob(cfg, wopts, hostJS))
+
+	var finished finishedImages
+	imageJobs, imgWG := startImageWorkers(ctx, wopts.imgWorkers, time.Duration(wopts.imgTimeout)*time.Second, wopts.dirs, repo,
+		func(job imageJob, meta *images.ImageMetadata, err error) {
+			// as in a local crawl, a DB error after a download is not a
+			// failed image; a canceled one is left for another node
+			if meta != nil {
+				finished.add(cluster.ImageDone{ID: job.TaskID, Format: meta.Format, Bytes: meta.Size})
+			} else if ctx.Err() == nil {
+				finished.add(cluster.ImageDone{ID: job.TaskID, Err: fetch.Label(err)})
+			}
+			recordImage(ctx, graph, job, meta, err)
+		})
+	// queueImages hands tasks to the image workers without holding up the
+	// lease loop: its leases and reports keep the assignments alive.
+	var queuing sync.WaitGroup
+	queueImages := func(tasks []cluster.ImageTask, crawlID int64) {
+		if len(tasks) == 0 {
+			return
+		}
+		queuing.Go(func() {
+			for _, t := range tasks {
+				select {
+				case imageJobs <- imageJob{Ref: t.Ref, PageURL: t.PageURL, CrawlID: crawlID, TaskID: t.ID}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+
+	client := cluster.NewClient(*coordURL, *nodeID)
+	failures := 0
+
+	for ctx.Err() == nil {
+		done := finished.take()
+		lease, err := client.Lease(ctx, wopts.workers, done)
+		if err != nil {
+			finished.add(done...)
+			failures++
+			slog.Warn("lease failed", "err", err, "failures", failures)
+			if failures >= *retries {
+				break
+			}
+			sleep(ctx, time.Second)
+			continue
+		}
+		failures = 0
+
+		if lease.Done {
+			slog.Info("coordinator reports crawl done")
+			break
+		}
+		graph.crawlID.Store(lease.CrawlID)
+		if len(lease.Images) > 0 {
+			slog.Info("took over images of a silent node", "images", len(lease.Images))
+			queueImages(lease.Images, lease.CrawlID)
+		}
+		if len(lease.Jobs) == 0 {
+			sleep(ctx, 500*time.Millisecond)
+			continue
+		}
+		slog.Debug("leased jobs", "lease", lease.LeaseID, "jobs", len(lease.Jobs))
+
+		go func(jobs []crawler.CrawlJob) {
+			for _, job := range jobs {
+				if wopts.replay != "" {
+					job.UseJS = false // the browser would fetch live
+				}
+				select {
+				case pool.Jobs() <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(lease.Jobs)
+
+		// Results are stored only once the coordinator accepted the report;
+		// after a rejected one the pages are crawled again elsewhere.
+		fetched := make([]crawler.CrawlResult, 0, len(lease.Jobs))
+		results := make([]cluster.Result, 0, len(lease.Jobs))
+		for len(results) < len(lease.Jobs) && ctx.Err() == nil {
+			select {
+			case res := <-pool.Results():
+				observeLimit(pool.Limiter, res.URL)
+				if res.Err == nil || ctx.Err() == nil {
+					observePage(res)
+				}
+				fetched = append(fetched, res)
+				results = append(results, cluster.NewResult(res))
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		done = finished.take()
+		report, err := client.Report(ctx, cluster.ReportRequest{
+			LeaseID: lease.LeaseID,
+			Results: results,
+			Images:  done,
+		})
+		if errors.Is(err, cluster.ErrLeaseExpired) {
+			slog.Warn("lease expired, results discarded", "lease", lease.LeaseID, "pages", len(results))
+			continue
+		}
+		if err != nil {
+			// The lease expires on the coordinator and the jobs are retried elsewhere.
+			slog.Warn("report failed", "lease", lease.LeaseID, "err", err)
+			finished.add(done...)
+			continue
+		}
+		for _, res := range fetched {
+			switch {
+			case res.Err == nil && res.Skipped == "":
+				graph.add(res)
+			case res.Err != nil:
+				graph.fail("page", res.URL, res.Err)
+			}
+		}
+
+		queueImages(report.Images, lease.CrawlID)
+	}
+
+	slog.Info("shutting down workers")
+	queuing.Wait()
+	close(imageJobs)
+	imgWG.Wait()
+	pool.Stop()
+	graph.close()
+	slog.Info("node exit")
+}
+
+// finishedImages collects the outcomes of a node's image tasks until its
+// next lease or report passes them to the coordinator.
+type finishedImages struct {
+	mu   sync.Mutex
+	done []cluster.ImageDone
+}
+
+func (f *finishedImages) add(done ...cluster.ImageDone) {
+	f.mu.Lock()
+	f.done = append(f.done, done...)
+	f.mu.Unlock()
+}
+
+func (f *finishedImages) take() []cluster.ImageDone {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	done := f.done
+	f.done = nil
+	return done
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
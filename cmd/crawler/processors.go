@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"GoCrawler/internal/config"
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/selector"
+)
+
+// pageProcessors are the page processors --processors can enable, by name.
+// Site-specific extractors are added here.
+var pageProcessors = map[string]func() crawler.PageProcessor{
+	"opengraph": func() crawler.PageProcessor { return crawler.OpenGraph{} },
+}
+
+// newCrawler returns a crawler running the extraction rules from cfg, if
+// any, and then the comma-separated processors in list, in order. cfg may
+// be nil.
+func newCrawler(cfg *config.Config, list string) (*crawler.Crawler, error) {
+	c := crawler.NewCrawler()
+	if cfg != nil && len(cfg.Extract) > 0 {
+		rules, err := extractRules(cfg.Extract)
+		if err != nil {
+			return nil, err
+		}
+		c.Register(rules)
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		newProcessor, ok := pageProcessors[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown page processor %q (available: %s)", name, strings.Join(slices.Sorted(maps.Keys(pageProcessors)), ", "))
+		}
+		c.Register(newProcessor())
+	}
+	return c, nil
+}
+
+// extractRules compiles the extraction rules of the config file.
+func extractRules(rules []config.ExtractRule) (crawler.Rules, error) {
+	var out crawler.Rules
+	for i, r := range rules {
+		re, err := regexp.Compile(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("extract[%d].url: %w", i, err)
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.Fields)) {
+			f := r.Fields[name]
+			sel, err := selector.Compile(f.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("extract[%d].fields.%s.selector: %w", i, name, err)
+			}
+			out = append(out, crawler.FieldRule{URL: re, Name: name, Selector: sel, Attr: strings.ToLower(f.Attr), All: f.All})
+		}
+	}
+	return out, nil
+}
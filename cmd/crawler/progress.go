@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+)
+
+// progress tracks live crawl activity for --progress. On a terminal it
+// redraws a dashboard in place; otherwise it logs a summary line every
+// interval. All methods are safe for concurrent use.
+type progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	started  time.Time
+	timeout  time.Duration
+	workers  int
+	imgWork  int
+	interval time.Duration
+
+	queue, inFlight, backlog int
+	pagesOK, pagesFailed     int
+	imagesOK, imagesFailed   int
+	imagesActive             int
+	errors                   map[string]int
+	hosts                    map[string]*hostActivity
+	limiter                  *crawler.HostLimiter // per-host limits with --adaptive
+	lastPages, lastImages    int
+	lastTick                 time.Time
+	pageRate, imageRate      float64
+	lines                    int        // lines of the last frame, erased before the next
+	width                    func() int // terminal columns, 0 if unknown
+	closed                   bool
+	stop                     chan struct{}
+	done                     chan struct{}
+}
+
+type hostActivity struct {
+	active, done, failed int
+}
+
+// isTerminal reports whether f is a character device, i.e. an interactive
+// terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func newProgress(out io.Writer, tty bool, timeout time.Duration, workers, imgWorkers int, interval time.Duration) *progress {
+	now := time.Now()
+	width := func() int { return 0 }
+	if f, ok := out.(*os.File); ok {
+		width = func() int { return terminalWidth(f) }
+	}
+	return &progress{
+		width:    width,
+		out:      out,
+		tty:      tty,
+		started:  now,
+		timeout:  timeout,
+		workers:  workers,
+		imgWork:  imgWorkers,
+		interval: interval,
+		errors:   make(map[string]int),
+		hosts:    make(map[string]*hostActivity),
+		lastTick: now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// start refreshes the display until close is called. Like every other
+// method it is a no-op on a nil *progress, i.e. without --progress.
+func (p *progress) start() {
+	if p == nil {
+		return
+	}
+	every := p.interval
+	if p.tty {
+		every = 500 * time.Millisecond
+	}
+	go func() {
+		defer close(p.done)
+		tick := time.NewTicker(every)
+		defer tick.Stop()
+		for {
+			select {
+			case <-p.stop:
+				p.refresh()
+				return
+			case <-tick.C:
+				p.refresh()
+			}
+		}
+	}()
+}
+
+// close draws the final frame and stops refreshing. The frame stays on
+// screen; later log output is printed below it.
+func (p *progress) close() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+}
+
+// setLimiter shows the per-host concurrency limits of l.
+func (p *progress) setLimiter(l *crawler.HostLimiter) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.limiter = l
+	p.mu.Unlock()
+}
+
+// setQueues records the dispatch loop's queue sizes.
+func (p *progress) setQueues(queue, inFlight, backlog int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.queue, p.inFlight, p.backlog = queue, inFlight, backlog
+	p.mu.Unlock()
+}
+
+func (p *progress) host(url string) *hostActivity {
+	h := crawler.HostOf(url)
+	a := p.hosts[h]
+	if a == nil {
+		a = &hostActivity{}
+		p.hosts[h] = a
+	}
+	return a
+}
+
+func (p *progress) dispatched(url string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.host(url).active++
+	p.mu.Unlock()
+}
+
+// page records a finished page. Aborted pages pass counted=false: they only
+// stop being active.
+func (p *progress) page(url string, err error, counted bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.host(url)
+	h.active--
+	if !counted {
+		return
+	}
+	h.done++
+	if err != nil {
+		h.failed++
+		p.pagesFailed++
+		p.errors["page "+fetch.Label(err)]++
+		return
+	}
+	p.pagesOK++
+}
+
+func (p *progress) imageDispatched() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.imagesActive++
+	p.mu.Unlock()
+}
+
+// image records a finished image job; see page for counted.
+func (p *progress) image(err error, counted bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.imagesActive--
+	if !counted {
+		return
+	}
+	if err != nil {
+		p.imagesFailed++
+		p.errors["image "+fetch.Label(err)]++
+		return
+	}
+	p.imagesOK++
+}
+
+func (p *progress) refresh() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if dt := now.Sub(p.lastTick).Seconds(); dt > 0 {
+		p.pageRate = float64(p.pagesOK+p.pagesFailed-p.lastPages) / dt
+		p.imageRate = float64(p.imagesOK+p.imagesFailed-p.lastImages) / dt
+	}
+	p.lastTick, p.lastPages, p.lastImages = now, p.pagesOK+p.pagesFailed, p.imagesOK+p.imagesFailed
+
+	if !p.tty {
+		slog.Info("progress",
+			"elapsed", now.Sub(p.started).Round(time.Second),
+			"pages", p.pagesOK, "pages_failed", p.pagesFailed, "pages_per_sec", round1(p.pageRate),
+			"images", p.imagesOK, "images_failed", p.imagesFailed, "images_per_sec", round1(p.imageRate),
+			"queue", p.queue, "in_flight", p.inFlight, "image_backlog", p.backlog, "images_active", p.imagesActive)
+		return
+	}
+	p.redrawLocked(nil)
+}
+
+// Write prints log output above the dashboard, so the logger and the
+// dashboard can share the terminal.
+func (p *progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return p.out.Write(b)
+	}
+	p.redrawLocked(b)
+	return len(b), nil
+}
+
+// redrawLocked erases the last frame, prints above (if any) and draws a new
+// frame below it.
+func (p *progress) redrawLocked(above []byte) {
+	var buf bytes.Buffer
+	if p.lines > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dA\x1b[J", p.lines)
+	}
+	buf.Write(above)
+
+	// a wrapped line would take more rows than the cursor-up counts
+	frame := fitWidth(p.frameLocked(time.Now()), p.columns())
+	buf.WriteString(frame)
+	p.lines = strings.Count(frame, "\n")
+	p.out.Write(buf.Bytes())
+}
+
+func (p *progress) frameLocked(now time.Time) string {
+	var b strings.Builder
+	elapsed := now.Sub(p.started)
+	total := elapsed.Seconds()
+
+	if p.timeout > 0 {
+		fmt.Fprintf(&b, "── GoCrawler ── elapsed %s / %s %s\n",
+			elapsed.Round(time.Second), p.timeout, bar(elapsed.Seconds()/p.timeout.Seconds(), 20))
+	} else {
+		fmt.Fprintf(&b, "── GoCrawler ── elapsed %s\n", elapsed.Round(time.Second))
+	}
+	fmt.Fprintf(&b, "pages   %6d ok %5d failed  %7.1f/s  (avg %.1f/s)\n",
+		p.pagesOK, p.pagesFailed, p.pageRate, float64(p.pagesOK+p.pagesFailed)/max(total, 1))
+	fmt.Fprintf(&b, "images  %6d ok %5d failed  %7.1f/s  (avg %.1f/s)\n",
+		p.imagesOK, p.imagesFailed, p.imageRate, float64(p.imagesOK+p.imagesFailed)/max(total, 1))
+	fmt.Fprintf(&b, "queue   %6d   in flight %d (%d workers)   image backlog %d   images active %d (%d workers)\n",
+		p.queue, p.inFlight, p.workers, p.backlog, p.imagesActive, p.imgWork)
+
+	if len(p.errors) > 0 {
+		keys := slices.Sorted(maps.Keys(p.errors))
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, fmt.Sprintf("%s:%d", k, p.errors[k]))
+		}
+		fmt.Fprintf(&b, "errors  %s\n", strings.Join(parts, "  "))
+	}
+
+	// Busiest hosts first: most active, then most done.
+	hosts := slices.Collect(maps.Keys(p.hosts))
+	slices.SortFunc(hosts, func(x, y string) int {
+		a, c := p.hosts[x], p.hosts[y]
+		if a.active != c.active {
+			return c.active - a.active
+		}
+		if a.done != c.done {
+			return c.done - a.done
+		}
+		return strings.Compare(x, y)
+	})
+	for i, h := range hosts {
+		if i == 5 {
+			fmt.Fprintf(&b, "        … %d more hosts\n", len(hosts)-5)
+			break
+		}
+		a := p.hosts[h]
+		fmt.Fprintf(&b, "host    %-32s active %3d  done %6d  failed %5d", h, a.active, a.done, a.failed)
+		if p.limiter != nil {
+			fmt.Fprintf(&b, "  limit %3d", p.limiter.Limit(h))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// columns is the width to fit frames to: the terminal's, else $COLUMNS,
+// else 80.
+func (p *progress) columns() int {
+	if w := p.width(); w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 80
+}
+
+// fitWidth cuts the lines of s longer than width columns, ending them with
+// an ellipsis. Every rune counts as one column.
+func fitWidth(s string, width int) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		if utf8.RuneCountInString(text) <= width {
+			continue
+		}
+		r := []rune(text)
+		lines[i] = string(r[:max(width-1, 0)]) + "…" + line[len(text):]
+	}
+	return strings.Join(lines, "")
+}
+
+// bar draws a progress bar of width cells for fraction f.
+func bar(f float64, width int) string {
+	if math.IsNaN(f) {
+		f = 0
+	}
+	f = min(max(f, 0), 1)
+	n := int(f * float64(width))
+	return "[" + strings.Repeat("#", n) + strings.Repeat("-", width-n) + "]"
+}
+
+func round1(f float64) float64 {
+	return float64(int(f*10+0.5)) / 10
+}
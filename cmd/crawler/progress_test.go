@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{0, "[----]"},
+		{0.5, "[##--]"},
+		{0.99, "[###-]"},
+		{1, "[####]"},
+		{2, "[####]"},
+		{-1, "[----]"},
+		{math.NaN(), "[----]"},
+		{math.Inf(1), "[####]"},
+	}
+	for _, tt := range tests {
+		if got := bar(tt.f, 4); got != tt.want {
+			t.Errorf("bar(%v, 4) = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestFitWidth(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"abc\n", 3, "abc\n"},
+		{"abcd\n", 3, "ab…\n"},
+		{"── x\nshort\n", 3, "──…\nsh…\n"},
+		{"abcd\nab\n", 3, "ab…\nab\n"},
+		{"abcd", 2, "a…"},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := fitWidth(tt.in, tt.width); got != tt.want {
+			t.Errorf("fitWidth(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestProgressFrame(t *testing.T) {
+	var out bytes.Buffer
+	p := newProgress(&out, true, 0, 4, 2, time.Second)
+	p.width = func() int { return 40 }
+	p.dispatched("https://a-very-long-host-name-that-would-wrap.example/")
+	p.page("https://a-very-long-host-name-that-would-wrap.example/", errors.New("boom"), true)
+	p.setQueues(3, 1, 0)
+
+	frame := p.frameLocked(time.Now())
+	if strings.Contains(frame, "NaN") || strings.Contains(frame, "[") {
+		t.Errorf("frame without a timeout shows a progress bar:\n%s", frame)
+	}
+	if !strings.Contains(frame, "errors  page other:1") {
+		t.Errorf("frame lacks the page error:\n%s", frame)
+	}
+
+	p.redrawLocked([]byte("log line\n"))
+	p.redrawLocked(nil)
+	frames := strings.Split(out.String(), "\x1b[")
+	last := frames[len(frames)-1]
+	last = last[strings.Index(last, "J")+1:]
+	for _, line := range strings.Split(strings.TrimSuffix(last, "\n"), "\n") {
+		if n := utf8.RuneCountInString(line); n > 40 {
+			t.Errorf("line of %d columns on a 40-column terminal: %q", n, line)
+		}
+	}
+	if want := strings.Count(last, "\n"); p.lines != want {
+		t.Errorf("lines = %d, want %d for the cursor-up of the next frame", p.lines, want)
+	}
+	if !strings.Contains(out.String(), "log line\n") {
+		t.Error("output lacks the line printed above the dashboard")
+	}
+
+	p.timeout = time.Minute
+	if frame := p.frameLocked(p.started.Add(30 * time.Second)); !strings.Contains(frame, "/ 1m0s [##########----------]") {
+		t.Errorf("frame at half the timeout lacks a half-full bar:\n%s", frame)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/storage"
+)
+
+const reportUsage = `usage: crawler report <command> [flags]
+
+commands:
+  inbound   pages ranked by number of linking pages
+  orphans   crawled pages no other crawled page links to
+  path      shortest click path from the seed to a page (--to URL)
+  broken    failed pages and images grouped by the page referencing them
+  variants  pages grouped into hreflang variant clusters`
+
+// runReport answers queries over the stored crawl data.
+func runReport(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, reportUsage)
+		os.Exit(2)
+	}
+	cmd, args := args[0], args[1:]
+	ctx := context.Background()
+
+	switch cmd {
+	case "inbound":
+		fs := flag.NewFlagSet("report inbound", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		limit := fs.Int("limit", 50, "Number of pages to list")
+		fs.Parse(args)
+
+		pages := storage.NewPageRepository(openStore(loadConfig(*cfgPath)))
+		counts, err := pages.InboundLinkCounts(ctx, *limit)
+		if err != nil {
+			logging.Fatal("report inbound", "err", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "INBOUND\tURL")
+		for _, c := range counts {
+			fmt.Fprintf(tw, "%d\t%s\n", c.Count, c.URL)
+		}
+		tw.Flush()
+
+	case "orphans":
+		fs := flag.NewFlagSet("report orphans", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		fs.Parse(args)
+
+		pages := storage.NewPageRepository(openStore(loadConfig(*cfgPath)))
+		orphans, err := pages.OrphanPages(ctx)
+		if err != nil {
+			logging.Fatal("report orphans", "err", err)
+		}
+		for _, u := range orphans {
+			fmt.Println(u)
+		}
+		fmt.Println(len(orphans), "orphan pages")
+
+	case "path":
+		fs := flag.NewFlagSet("report path", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		to := fs.String("to", "", "Target page URL (required)")
+		from := fs.String("from", "", "Start page URL (default: every seed)")
+		maxHops := fs.Int("max-hops", 50, "Give up after this many clicks")
+		fs.Parse(args)
+		if *to == "" {
+			logging.Fatal("missing required flag: --to")
+		}
+
+		pages := storage.NewPageRepository(openStore(loadConfig(*cfgPath)))
+		path, err := pages.ClickPath(ctx, *from, *to, *maxHops)
+		if err != nil {
+			logging.Fatal("report path", "err", err)
+		}
+		if path == nil {
+			fmt.Println("no path to", *to)
+			os.Exit(1)
+		}
+		for i, u := range path {
+			fmt.Printf("%d\t%s\n", i, u)
+		}
+
+	case "broken":
+		fs := flag.NewFlagSet("report broken", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		kind := fs.String("kind", "", "Only list broken targets of this kind: page or image")
+		fs.Parse(args)
+		if *kind != "" && *kind != "page" && *kind != "image" {
+			logging.Fatal("--kind must be page or image")
+		}
+
+		pages := storage.NewPageRepository(openStore(loadConfig(*cfgPath)))
+		broken, err := pages.BrokenLinks(ctx, *kind)
+		if err != nil {
+			logging.Fatal("report broken", "err", err)
+		}
+		printBroken(broken)
+
+	case "variants":
+		fs := flag.NewFlagSet("report variants", flag.ExitOnError)
+		cfgPath := registerConfig(fs)
+		crawlID := fs.Int64("crawl", 0, "Only pages stored by this crawl")
+		fs.Parse(args)
+
+		pages := storage.NewPageRepository(openStore(loadConfig(*cfgPath)))
+		variants, err := pages.Variants(ctx, *crawlID)
+		if err != nil {
+			logging.Fatal("report variants", "err", err)
+		}
+		printVariants(variants)
+
+	default:
+		fmt.Fprintln(os.Stderr, reportUsage)
+		os.Exit(2)
+	}
+}
+
+// printBroken prints broken targets under the page referencing them. The
+// rows arrive ordered by referrer.
+func printBroken(broken []storage.BrokenLink) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	targets := make(map[string]struct{})
+	referrer := "\x00"
+	for _, b := range broken {
+		if b.ReferrerURL != referrer {
+			referrer = b.ReferrerURL
+			if referrer == "" {
+				fmt.Fprintln(tw, "\n(no referrer)")
+			} else if b.ReferrerTitle != "" {
+				fmt.Fprintf(tw, "\n%s  (%s)\n", referrer, b.ReferrerTitle)
+			} else {
+				fmt.Fprintf(tw, "\n%s\n", referrer)
+			}
+		}
+		status := b.ErrorClass
+		if b.StatusCode != 0 {
+			status = fmt.Sprint(b.StatusCode)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%q\n", status, b.Kind, b.URL, b.AnchorText)
+		targets[b.URL] = struct{}{}
+	}
+	tw.Flush()
+	fmt.Println()
+	fmt.Println(len(targets), "broken targets")
+}
+
+// printVariants prints each variant cluster as a block of its pages. The
+// rows arrive ordered by cluster.
+func printVariants(variants []storage.PageVariant) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	clusters := 0
+	group := ""
+	for _, v := range variants {
+		if v.Group != group {
+			group = v.Group
+			clusters++
+			fmt.Fprintln(tw, "\nHREFLANG\tLANG\tURL")
+		}
+		hreflang := v.Hreflang
+		if hreflang == "" {
+			hreflang = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", hreflang, v.Lang, v.URL)
+	}
+	tw.Flush()
+	fmt.Println()
+	fmt.Println(clusters, "variant clusters,", len(variants), "pages")
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"GoCrawler/internal/config"
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+	"GoCrawler/internal/logging"
+	"GoCrawler/internal/storage"
+	"GoCrawler/internal/warc"
+
+	"github.com/chromedp/chromedp"
+)
+
+// browserContext starts a chromedp browser when useJS is set. The returned
+// cancel func must always be called.
+func browserContext(useJS bool) (context.Context, context.CancelFunc) {
+	baseCtx := context.Background()
+	if !useJS {
+		return baseCtx, func() {}
+	}
+
+	slog.Info("starting chromedp browser")
+	allocCtx, allocCancel := chromedp.NewExecAllocator(baseCtx, chromedp.DefaultExecAllocatorOptions[:]...)
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+
+	return browserCtx, func() {
+		browserCancel()
+		allocCancel()
+	}
+}
+
+// applyLogging installs the --log-level/--log-format logger.
+func applyLogging(o logging.Options) {
+	applyLoggingTo(o, os.Stderr)
+}
+
+func applyLoggingTo(o logging.Options, w io.Writer) {
+	if err := o.ApplyTo(w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// registerConfig adds the --config flag.
+func registerConfig(fs *flag.FlagSet) *string {
+	return fs.String("config", "", "Config file, JSON, YAML or TOML (default $"+config.EnvFile+")")
+}
+
+// loadConfig reads the config file at path and the GOCRAWLER_* environment
+// overrides.
+func loadConfig(path string) *config.Config {
+	cfg, err := config.Load(path)
+	if err != nil {
+		logging.Fatal("load config", "err", err)
+	}
+	return cfg
+}
+
+// applyFetchConfig sets the request defaults every fetch uses.
+func applyFetchConfig(cfg *config.Config) {
+	fetch.UserAgent = cfg.Fetch.UserAgent
+	fetch.PageTimeout = cfg.Fetch.PageTimeout.Std()
+	fetch.ImageTimeout = cfg.Fetch.ImageTimeout.Std()
+}
+
+// startHostRules wraps the installed transport with the configured
+// per-host delays and headers. Replayed fetches never reach a host, so
+// there is nothing to do in replay mode.
+func startHostRules(cfg *config.Config, o workerOptions) {
+	if !cfg.HasHostRules() || o.replay != "" {
+		return
+	}
+	fetch.Hosts = &fetch.HostTransport{
+		Next: fetch.BaseTransport(),
+		Rules: func(host string) fetch.HostRule {
+			return fetch.HostRule{Delay: cfg.Delay(host), Headers: cfg.Headers(host)}
+		},
+	}
+	fetch.Transport = fetch.Hosts
+	slog.Info("per-host fetch rules", "delay", cfg.Fetch.Delay.Std(), "hosts", len(cfg.Hosts))
+}
+
+// processJob runs the extraction rules from cfg and the page processors
+// selected in o on every job, with the per-host JS setting from cfg
+// applied when hostJS is set.
+func processJob(cfg *config.Config, o workerOptions, hostJS bool) func(context.Context, crawler.CrawlJob) crawler.CrawlResult {
+	c, err := newCrawler(cfg, o.processors)
+	if err != nil {
+		logging.Fatal("page processors", "err", err)
+	}
+	if names := c.Processors(); len(names) > 0 {
+		slog.Info("page processors", "processors", names, "extract_rules", len(cfg.Extract))
+	}
+	if !hostJS || len(cfg.Hosts) == 0 {
+		return c.Process
+	}
+	return func(ctx context.Context, job crawler.CrawlJob) crawler.CrawlResult {
+		job.UseJS = cfg.UseJS(job.URL, job.UseJS)
+		return c.Process(ctx, job)
+	}
+}
+
+func ensureDirs(dirs config.Dirs) {
+	if err := os.MkdirAll(dirs.Images, 0o755); err != nil {
+		logging.Fatal("create images dir", "err", err)
+	}
+	if err := os.MkdirAll(dirs.Thumbnails, 0o755); err != nil {
+		logging.Fatal("create thumbnails dir", "err", err)
+	}
+	slog.Debug("ensured image dirs", "images", dirs.Images, "thumbnails", dirs.Thumbnails)
+}
+
+func openStore(cfg *config.Config) *storage.MySQLStorage {
+	store, err := storage.OpenMySQL(cfg.Database.DSN)
+	if err != nil {
+		logging.Fatal("connect to MySQL", "err", err)
+	}
+	slog.Info("database connected")
+	return store
+}
+
+// startWARC installs a recording transport when --warc-dir is set. The
+// returned func flushes and closes the current WARC file.
+func startWARC(o workerOptions) func() {
+	if o.warcDir == "" {
+		return func() {}
+	}
+	w, err := warc.NewWriter(o.warcDir, o.warcPrefix, int64(o.warcMaxMB)<<20)
+	if err != nil {
+		logging.Fatal("create WARC dir", "err", err)
+	}
+	w.Info = map[string]string{"http-header-user-agent": fetch.UserAgent}
+	fetch.Transport = &warc.Recorder{Next: fetch.BaseTransport(), Writer: w}
+	slog.Info("recording WARC", "dir", o.warcDir)
+
+	return func() {
+		if err := w.Close(); err != nil {
+			slog.Error("close WARC", "err", err)
+		}
+	}
+}
+
+// startReplay points all fetches at the WARC files named by --replay. The
+// returned func logs how many requests the archive could answer.
+func startReplay(o workerOptions) func() {
+	if o.replay == "" {
+		return func() {}
+	}
+	archive, err := warc.LoadArchive(strings.Split(o.replay, ",")...)
+	if err != nil {
+		logging.Fatal("load WARC archive", "err", err)
+	}
+	fetch.Transport = archive
+	slog.Info("replaying from WARC", "urls", archive.Len(), "paths", o.replay)
+
+	return func() {
+		slog.Info("replay done", "hits", archive.Hits.Load(), "misses", archive.Misses.Load())
+	}
+}
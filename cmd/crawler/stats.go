@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"GoCrawler/internal/stats"
+	"GoCrawler/internal/storage"
+)
+
+// saveReport writes the statistics report to path, if set, and stores it
+// with the crawl record.
+func saveReport(crawls *storage.CrawlRepository, crawlID int64, report *stats.Report, path string) {
+	if path != "" {
+		if err := report.WriteFile(path); err != nil {
+			slog.Error("write stats report", "path", path, "err", err)
+		} else {
+			slog.Info("wrote stats report", "path", path)
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		slog.Error("encode stats report", "err", err)
+		data = nil
+	}
+	finishCrawlRecord(crawls, crawlID, report.Outcome, data)
+}
@@ -1,15 +1,35 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"GoCrawler/internal/config"
+	"GoCrawler/internal/export"
+	"GoCrawler/internal/logging"
 	"GoCrawler/internal/storage"
 )
 
 type TemplateData struct {
-	Results []ImageResult
+	Results    []ImageResult
+	Crawls     []storage.CrawlRecord // for the crawl filter
+	Crawl      int64                 // selected crawl, 0 for all
+	FieldNames []string              // for the field filter
+	Field      string
+	Value      string
+	Languages  []string // for the language filter
+	Lang       string
+	ExportQS   template.URL // query string of the export links
 }
 
 type ImageResult struct {
@@ -18,36 +38,138 @@ type ImageResult struct {
 	Filename  string
 	Format    string
 	URL       string
+	Alt       string
+	Caption   string
+	Heading   string
+	PageURL   string
+	PageTitle string
+	Fields    []storage.PageField // extracted from the page
+}
+
+// PagesData is the page search view.
+type PagesData struct {
+	Query     string
+	Lang      string
+	Crawl     int64
+	Languages []string              // for the language filter
+	Crawls    []storage.CrawlRecord // for the crawl filter
+	Results   []PageResult
+	Page      int          // 1-based
+	PrevQS    template.URL // query string of the previous page, "" on the first
+	NextQS    template.URL // ... of the next page, "" on the last
+}
+
+// PageResult is a page matching the search, with the query terms
+// highlighted.
+type PageResult struct {
+	URL         string
+	Lang        string
+	Title       template.HTML
+	Description template.HTML
+	Snippet     template.HTML
+	Images      []ImageResult
+}
+
+// pageSearchSize is how many pages the page search shows at once.
+const pageSearchSize = 20
+
+// BrokenGroup is the broken targets referenced from one page.
+type BrokenGroup struct {
+	Referrer string
+	Title    string
+	Items    []storage.BrokenLink
+}
+
+type CrawlsData struct {
+	Filter storage.CrawlFilter
+	Crawls []storage.CrawlRecord
+}
+
+type BrokenData struct {
+	Kind    string
+	Targets int
+	Groups  []BrokenGroup
 }
 
 func main() {
-	store, err := storage.NewMySQLStorage(
-		"crawler",
-		"password123",
-		"localhost:3306",
-		"crawlerdb",
-	)
+	cfgPath := flag.String("config", "", "Config file, JSON, YAML or TOML (default $"+config.EnvFile+")")
+	var lopts logging.Options
+	lopts.Register(flag.CommandLine)
+	flag.Parse()
+	if err := lopts.Apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		logging.Fatal("load config", "err", err)
+	}
+	store, err := storage.OpenMySQL(cfg.Database.DSN)
 	if err != nil {
-		log.Fatal("DB error:", err)
+		logging.Fatal("connect to MySQL", "err", err)
 	}
 	repo := storage.NewImageRepository(store)
+	pages := storage.NewPageRepository(store)
+	crawls := storage.NewCrawlRepository(store)
 
 	tmpl, err := template.ParseFiles("internal/web/templates/index.html")
 	if err != nil {
-		log.Fatal("template parse error:", err)
+		logging.Fatal("template parse error", "err", err)
 	}
 
-	http.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("./images"))))
-	http.Handle("/thumbnails/", http.StripPrefix("/thumbnails/", http.FileServer(http.Dir("./thumbnails"))))
+	pagesTmpl, err := template.ParseFiles("internal/web/templates/pages.html")
+	if err != nil {
+		logging.Fatal("template parse error", "err", err)
+	}
+
+	brokenTmpl, err := template.ParseFiles("internal/web/templates/broken.html")
+	if err != nil {
+		logging.Fatal("template parse error", "err", err)
+	}
+
+	crawlsTmpl, err := template.New("crawls.html").Funcs(template.FuncMap{
+		"duration": func(c storage.CrawlRecord) string {
+			if c.FinishedAt == nil {
+				return "-"
+			}
+			return c.FinishedAt.Sub(c.StartedAt).Round(time.Second).String()
+		},
+	}).ParseFiles("internal/web/templates/crawls.html")
+	if err != nil {
+		logging.Fatal("template parse error", "err", err)
+	}
+
+	http.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir(cfg.Dirs.Images))))
+	http.Handle("/thumbnails/", http.StripPrefix("/thumbnails/", http.FileServer(http.Dir(cfg.Dirs.Thumbnails))))
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		params := map[string]string{
 			"format":   r.URL.Query().Get("format"),
 			"filename": r.URL.Query().Get("filename"),
 			"url":      r.URL.Query().Get("url"),
+			"q":        r.URL.Query().Get("q"),
+			"crawl":    r.URL.Query().Get("crawl"),
+			"field":    r.URL.Query().Get("field"),
+			"value":    r.URL.Query().Get("value"),
+			"lang":     r.URL.Query().Get("lang"),
 		}
 
+		start := time.Now()
 		results, err := repo.SearchImages(r.Context(), params)
+		observeSearch("images", start, len(results), err)
+		if err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		pageURLs := make([]string, 0, len(results))
+		for _, m := range results {
+			if m.PageURL != "" {
+				pageURLs = append(pageURLs, m.PageURL)
+			}
+		}
+		fields, err := pages.PageFields(r.Context(), pageURLs)
 		if err != nil {
 			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -56,20 +178,283 @@ func main() {
 		imgs := make([]ImageResult, 0, len(results))
 		for _, m := range results {
 			imgs = append(imgs, ImageResult{
-				Thumbnail: m.ThumbPath,
-				FullImage: m.SavedPath,
+				Thumbnail: webPath(cfg.Dirs, m.ThumbPath),
+				FullImage: webPath(cfg.Dirs, m.SavedPath),
 				Filename:  m.Filename,
 				Format:    m.Format,
 				URL:       m.OriginalURL,
+				Alt:       m.AltText,
+				Caption:   m.Caption,
+				Heading:   m.Heading,
+				PageURL:   m.PageURL,
+				PageTitle: m.PageTitle,
+				Fields:    fields[m.PageURL],
 			})
 		}
 
-		if err := tmpl.Execute(w, TemplateData{Results: imgs}); err != nil {
+		data := TemplateData{Results: imgs, Field: params["field"], Value: params["value"], Lang: params["lang"]}
+		data.Crawl, _ = strconv.ParseInt(params["crawl"], 10, 64)
+		if data.Crawls, err = crawls.ListCrawls(r.Context(), storage.CrawlFilter{}); err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if data.FieldNames, err = pages.FieldNames(r.Context()); err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if data.Languages, err = pages.Languages(r.Context()); err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		qs := url.Values{}
+		for _, k := range []string{"crawl", "field", "value"} {
+			if params[k] != "" {
+				qs.Set(k, params[k])
+			}
+		}
+		data.ExportQS = template.URL(qs.Encode())
+
+		if err := tmpl.Execute(w, data); err != nil {
 			http.Error(w, "template execute error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 	})
 
-	log.Println("Web server running at http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	http.HandleFunc("GET /pages", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		data := PagesData{Query: strings.TrimSpace(q.Get("q")), Lang: q.Get("lang"), Page: 1}
+		if c := q.Get("crawl"); c != "" {
+			id, err := strconv.ParseInt(c, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid crawl id", http.StatusBadRequest)
+				return
+			}
+			data.Crawl = id
+		}
+		if p := q.Get("page"); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 1 {
+				http.Error(w, "invalid page number", http.StatusBadRequest)
+				return
+			}
+			data.Page = n
+		}
+
+		var err error
+		if data.Query != "" {
+			start := time.Now()
+			terms := queryTerms(data.Query)
+			// one more than shown, to know whether there is a next page
+			hits, err := pages.SearchPages(r.Context(), storage.PageQuery{
+				Text:    data.Query,
+				Anchor:  terms.first(),
+				Lang:    data.Lang,
+				CrawlID: data.Crawl,
+				Limit:   pageSearchSize + 1,
+				Offset:  (data.Page - 1) * pageSearchSize,
+			})
+			observeSearch("pages", start, len(hits), err)
+			if err != nil {
+				http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			more := len(hits) > pageSearchSize
+			hits = hits[:min(len(hits), pageSearchSize)]
+			urls := make([]string, len(hits))
+			for i, h := range hits {
+				urls[i] = h.URL
+			}
+			imgs, err := pages.PageImages(r.Context(), urls, 0)
+			if err != nil {
+				http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			for _, h := range hits {
+				res := PageResult{
+					URL:         h.URL,
+					Lang:        h.Lang,
+					Title:       highlight(h.Title, terms),
+					Description: highlight(h.Description, terms),
+					Snippet:     snippet(h.Text, terms, h.TextBefore, h.TextAfter),
+				}
+				for _, m := range imgs[h.URL] {
+					res.Images = append(res.Images, ImageResult{
+						Thumbnail: webPath(cfg.Dirs, m.ThumbPath),
+						FullImage: webPath(cfg.Dirs, m.SavedPath),
+						Filename:  m.Filename,
+						URL:       m.OriginalURL,
+						Alt:       m.AltText,
+					})
+				}
+				data.Results = append(data.Results, res)
+			}
+
+			qs := url.Values{"q": {data.Query}}
+			if data.Lang != "" {
+				qs.Set("lang", data.Lang)
+			}
+			if data.Crawl != 0 {
+				qs.Set("crawl", strconv.FormatInt(data.Crawl, 10))
+			}
+			if data.Page > 1 {
+				qs.Set("page", strconv.Itoa(data.Page-1))
+				data.PrevQS = template.URL(qs.Encode())
+			}
+			if more {
+				qs.Set("page", strconv.Itoa(data.Page+1))
+				data.NextQS = template.URL(qs.Encode())
+			}
+		}
+
+		if data.Crawls, err = crawls.ListCrawls(r.Context(), storage.CrawlFilter{}); err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if data.Languages, err = pages.Languages(r.Context()); err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := pagesTmpl.Execute(w, data); err != nil {
+			http.Error(w, "template execute error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	http.HandleFunc("GET /export", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			http.Error(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+		f := storage.FieldFilter{Name: q.Get("field"), Value: q.Get("value")}
+		if c := q.Get("crawl"); c != "" {
+			id, err := strconv.ParseInt(c, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid crawl id", http.StatusBadRequest)
+				return
+			}
+			f.CrawlID = id
+		}
+
+		start := time.Now()
+		list, err := pages.ExportPages(r.Context(), f)
+		observeSearch("export", start, len(list), err)
+		if err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="pages.`+format+`"`)
+		if err := export.Write(w, format, list); err != nil {
+			slog.Error("write export", "err", err)
+		}
+	})
+
+	http.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		kind := r.URL.Query().Get("kind")
+		if kind != "" && kind != "page" && kind != "image" {
+			http.Error(w, "kind must be page or image", http.StatusBadRequest)
+			return
+		}
+
+		start := time.Now()
+		broken, err := pages.BrokenLinks(r.Context(), kind)
+		observeSearch("broken", start, len(broken), err)
+		if err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data := BrokenData{Kind: kind}
+		targets := make(map[string]struct{})
+		for _, b := range broken {
+			// rows arrive ordered by referrer
+			if n := len(data.Groups); n == 0 || data.Groups[n-1].Referrer != b.ReferrerURL {
+				data.Groups = append(data.Groups, BrokenGroup{Referrer: b.ReferrerURL, Title: b.ReferrerTitle})
+			}
+			g := &data.Groups[len(data.Groups)-1]
+			g.Items = append(g.Items, b)
+			targets[b.URL] = struct{}{}
+		}
+		data.Targets = len(targets)
+
+		if err := brokenTmpl.Execute(w, data); err != nil {
+			http.Error(w, "template execute error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	http.HandleFunc("GET /crawls", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		data := CrawlsData{Filter: storage.CrawlFilter{Name: q.Get("name"), Seed: q.Get("seed"), Status: q.Get("status")}}
+
+		start := time.Now()
+		list, err := crawls.ListCrawls(r.Context(), data.Filter)
+		observeSearch("crawls", start, len(list), err)
+		if err != nil {
+			http.Error(w, "DB search error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data.Crawls = list
+
+		if err := crawlsTmpl.Execute(w, data); err != nil {
+			http.Error(w, "template execute error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+
+	http.HandleFunc("POST /crawls/{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+		if err := sameOrigin(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid crawl id", http.StatusBadRequest)
+			return
+		}
+		files, err := crawls.DeleteCrawl(r.Context(), id)
+		if errors.Is(err, storage.ErrNoCrawl) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "DB delete error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, f := range files {
+			if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("remove image file", "path", f, "err", err)
+			}
+		}
+		slog.Info("crawl deleted", "crawl_id", id, "files", len(files))
+		http.Redirect(w, r, "/crawls", http.StatusSeeOther)
+	})
+
+	http.Handle("GET /metrics", registry.Handler())
+
+	slog.Info("web server running", "addr", "http://localhost:8080")
+	err = http.ListenAndServe(":8080", instrument(http.DefaultServeMux))
+	logging.Fatal("web server stopped", "err", err)
+}
+
+// webPath maps a stored file path to the URL path it is served under:
+// files in the image directory live under images/, thumbnails under
+// thumbnails/.
+func webPath(dirs config.Dirs, p string) string {
+	for _, d := range []struct{ dir, prefix string }{
+		{dirs.Images, "images/"},
+		{dirs.Thumbnails, "thumbnails/"},
+	} {
+		if rel, err := filepath.Rel(d.dir, p); err == nil && filepath.IsLocal(rel) {
+			return d.prefix + filepath.ToSlash(rel)
+		}
+	}
+	return p
 }
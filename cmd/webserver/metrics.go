@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoCrawler/internal/metrics"
+)
+
+// Webserver metrics, served on /metrics.
+var (
+	registry = metrics.NewRegistry()
+
+	httpRequests = registry.NewCounterVec("webserver_http_requests_total", "HTTP requests, by route and status code.", "route", "code")
+	httpDuration = registry.NewHistogramVec("webserver_http_request_seconds", "HTTP request latency, by route.", nil, "route")
+
+	searchQueries  = registry.NewCounterVec("webserver_search_queries_total", "Search queries, by kind (images, pages, broken, ...) and result (ok, error).", "kind", "result")
+	searchDuration = registry.NewHistogramVec("webserver_search_query_seconds", "Search query latency in the database, by kind.", nil, "kind")
+	searchResults  = registry.NewHistogramVec("webserver_search_results", "Rows returned per search, by kind.", []float64{0, 1, 10, 50, 100, 500, 1000}, "kind")
+)
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// instrument counts, times and logs every request by route. Routes are the
+// first path segment, so file paths under /images/ don't create new series.
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := "/" + strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+		switch route {
+		case "/", "/pages", "/broken", "/crawls", "/export", "/images", "/thumbnails", "/metrics":
+		default:
+			route = "other"
+		}
+		elapsed := time.Since(start)
+		httpRequests.Inc(route, strconv.Itoa(rec.code))
+		httpDuration.Observe(elapsed.Seconds(), route)
+		slog.Debug("http request", "method", r.Method, "path", r.URL.Path, "query", r.URL.RawQuery, "code", rec.code, "duration", elapsed)
+	})
+}
+
+// observeSearch records one search query of kind that started at start.
+func observeSearch(kind string, start time.Time, rows int, err error) {
+	searchDuration.Observe(time.Since(start).Seconds(), kind)
+	if err != nil {
+		searchQueries.Inc(kind, "error")
+		return
+	}
+	searchQueries.Inc(kind, "ok")
+	searchResults.Observe(float64(rows), kind)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sameOrigin checks that r, a request that changes data, comes from a page
+// served by this server: its Origin header, or its Referer when there is no
+// Origin, must name the host the request was sent to. A request carrying
+// neither is refused too, so another site open in the operator's browser
+// cannot post forms here.
+func sameOrigin(r *http.Request) error {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return fmt.Errorf("cross-site request refused (Sec-Fetch-Site: %s)", site)
+	}
+	src, header := r.Header.Get("Origin"), "Origin"
+	if src == "" {
+		src, header = r.Header.Get("Referer"), "Referer"
+	}
+	if src == "" {
+		return errors.New("request without Origin or Referer header refused")
+	}
+	u, err := url.Parse(src)
+	if err != nil || u.Host == "" || !strings.EqualFold(u.Host, r.Host) {
+		return fmt.Errorf("cross-origin request refused (%s: %s)", header, src)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		name                 string
+		origin, referer, sfs string
+		ok                   bool
+	}{
+		{"same origin", "http://localhost:8080", "", "same-origin", true},
+		{"origin without fetch metadata", "http://localhost:8080", "", "", true},
+		{"referer only", "", "http://localhost:8080/crawls?name=x", "", true},
+		{"host case", "http://LOCALHOST:8080", "", "", true},
+		{"other site", "https://evil.example", "", "", false},
+		{"other port", "http://localhost:9999", "", "", false},
+		{"cross-site fetch metadata", "http://localhost:8080", "", "cross-site", false},
+		{"same-site fetch metadata", "http://localhost:8080", "", "same-site", false},
+		{"opaque origin", "null", "", "", false},
+		{"origin wins over referer", "https://evil.example", "http://localhost:8080/crawls", "", false},
+		{"other referer", "", "https://evil.example/page", "", false},
+		{"neither header", "", "", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "http://localhost:8080/crawls/1/delete", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.referer != "" {
+			r.Header.Set("Referer", tt.referer)
+		}
+		if tt.sfs != "" {
+			r.Header.Set("Sec-Fetch-Site", tt.sfs)
+		}
+		if err := sameOrigin(r); (err == nil) != tt.ok {
+			t.Errorf("%s: sameOrigin = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
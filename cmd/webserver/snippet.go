@@ -0,0 +1,154 @@
+package main
+
+import (
+	"html/template"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// snippetWidth is roughly how many bytes of page text a search result shows.
+const snippetWidth = 280
+
+// searchTerms are the words of a full-text query, to highlight in results.
+type searchTerms struct {
+	words  []string        // in query order
+	re     *regexp.Regexp  // any of the words, case-insensitively
+	prefix map[string]bool // words that also match longer words (boolean mode "word*")
+}
+
+// queryTerms returns the words of a full-text query, leaving out boolean
+// operators and excluded (-word) words; nil when there are none.
+func queryTerms(q string) *searchTerms {
+	t := &searchTerms{prefix: make(map[string]bool)}
+	var alts []string
+	seen := make(map[string]bool)
+	for _, tok := range strings.Fields(q) {
+		if strings.HasPrefix(tok, "-") {
+			continue
+		}
+		prefix := strings.HasSuffix(tok, "*")
+		words := strings.FieldsFunc(tok, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		for i, w := range words {
+			w = strings.ToLower(w)
+			if prefix && i == len(words)-1 {
+				t.prefix[w] = true
+			}
+			if seen[w] {
+				continue
+			}
+			seen[w] = true
+			t.words = append(t.words, w)
+			alts = append(alts, regexp.QuoteMeta(w))
+		}
+	}
+	if len(alts) == 0 {
+		return nil
+	}
+	// longest first, so "article" wins over "art"
+	slices.SortStableFunc(alts, func(a, b string) int { return len(b) - len(a) })
+	t.re = regexp.MustCompile(`(?i)` + strings.Join(alts, "|"))
+	return t
+}
+
+// first returns the first word of the query, "" for none.
+func (t *searchTerms) first() string {
+	if t == nil {
+		return ""
+	}
+	return t.words[0]
+}
+
+// matches returns the byte ranges in s where one of the words stands as a
+// word of its own (or starts one, for prefix words).
+func (t *searchTerms) matches(s string) [][]int {
+	if t == nil {
+		return nil
+	}
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	var out [][]int
+	for _, m := range t.re.FindAllStringIndex(s, -1) {
+		if r, _ := utf8.DecodeLastRuneInString(s[:m[0]]); m[0] > 0 && isWord(r) {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(s[m[1]:]); m[1] < len(s) && isWord(r) && !t.prefix[strings.ToLower(s[m[0]:m[1]])] {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// highlight escapes s and wraps the query terms in it in <mark>.
+func highlight(s string, terms *searchTerms) template.HTML {
+	var sb strings.Builder
+	last := 0
+	for _, m := range terms.matches(s) {
+		sb.WriteString(template.HTMLEscapeString(s[last:m[0]]))
+		sb.WriteString("<mark>")
+		sb.WriteString(template.HTMLEscapeString(s[m[0]:m[1]]))
+		sb.WriteString("</mark>")
+		last = m[1]
+	}
+	sb.WriteString(template.HTMLEscapeString(s[last:]))
+	return template.HTML(sb.String())
+}
+
+// snippet cuts about snippetWidth bytes of text around the first query term
+// in it (or from its start) and highlights the terms. before and after say
+// whether text is an excerpt of a longer text, so the cut is marked.
+func snippet(text string, terms *searchTerms, before, after bool) template.HTML {
+	text = strings.Join(strings.Fields(text), " ")
+	// an excerpt may start or end in the middle of a word
+	if i := strings.IndexByte(text, ' '); before && i >= 0 {
+		text = text[i+1:]
+	}
+	if i := strings.LastIndexByte(text, ' '); after && i >= 0 {
+		text = text[:i]
+	}
+	start := 0
+	end := len(text)
+	if len(text) > snippetWidth {
+		start, end = cut(text, terms)
+	}
+	out := highlight(text[start:end], terms)
+	if start > 0 || before {
+		out = "… " + out
+	}
+	if end < len(text) || after {
+		out += " …"
+	}
+	return out
+}
+
+// cut returns the byte range of about snippetWidth bytes of text around the
+// first query term in it, at word boundaries.
+func cut(text string, terms *searchTerms) (start, end int) {
+	if m := terms.matches(text); len(m) > 0 {
+		start = max(0, m[0][0]-snippetWidth/3)
+	}
+	end = min(len(text), start+snippetWidth)
+	if end == len(text) {
+		start = max(0, end-snippetWidth)
+	}
+	// cut at word boundaries
+	if start > 0 {
+		if i := strings.IndexByte(text[start:], ' '); i >= 0 && i < snippetWidth/4 {
+			start += i + 1
+		}
+	}
+	if end < len(text) {
+		if i := strings.LastIndexByte(text[start:end], ' '); i > 0 {
+			end = start + i
+		}
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	return start, end
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client talks to a Coordinator on behalf of one crawler node.
+type Client struct {
+	BaseURL string
+	Node    string
+	HTTP    *http.Client
+}
+
+func NewClient(baseURL, node string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Node:    node,
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Lease asks for up to max jobs and tells the coordinator which image
+// tasks finished.
+func (c *Client) Lease(ctx context.Context, max int, done []ImageDone) (*LeaseResponse, error) {
+	var resp LeaseResponse
+	err := c.post(ctx, "/lease", LeaseRequest{Node: c.Node, Max: max, Images: done}, &resp)
+	return &resp, err
+}
+
+func (c *Client) Report(ctx context.Context, req ReportRequest) (*ReportResponse, error) {
+	req.Node = c.Node
+	var resp ReportResponse
+	err := c.post(ctx, "/report", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) post(ctx context.Context, path string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return ErrLeaseExpired
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coordinator %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,366 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/stats"
+)
+
+type lease struct {
+	node    string
+	jobs    []crawler.CrawlJob
+	expires time.Time
+}
+
+// Coordinator owns the frontier and visited set of a distributed crawl and
+// hands out leased batches of jobs to crawler nodes over HTTP. Leases that
+// are not reported before they expire go back to the front of the queue,
+// and a late report for them is rejected so that the pages are only
+// crawled once: by the node that leases them next. Images assigned to a
+// node that has not leased or reported for the lease TTL are handed to the
+// next node that leases. The pages and images the nodes report are
+// collected for the crawl's statistics report.
+type Coordinator struct {
+	leaseTTL time.Duration
+
+	// CrawlID, if set before serving, is sent with every lease so nodes
+	// store their pages and images under the coordinator's crawl record.
+	CrawlID int64
+
+	// Hreflang, if set before serving, is the only hreflang variant crawled
+	// of pages that have variants; the other variants are never leased.
+	Hreflang string
+
+	mu         sync.Mutex
+	frontier   *crawler.Frontier
+	budget     *crawler.Budget
+	leases     map[string]*lease
+	nextID     int
+	seenImages map[string]struct{}
+	variants   *crawler.VariantImages
+	images     map[string]ImageTask // by ID, assigned and not reported done
+	assignee   map[string]string    // image ID -> node
+	requeued   []ImageTask          // assigned to nodes that went silent
+	nextImage  int
+	lastSeen   map[string]time.Time // node -> its last lease or report
+	draining   bool
+	status     Status
+	stats      *stats.Collector
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func NewCoordinator(frontier *crawler.Frontier, budget *crawler.Budget, leaseTTL time.Duration) *Coordinator {
+	return &Coordinator{
+		leaseTTL:   leaseTTL,
+		frontier:   frontier,
+		budget:     budget,
+		leases:     make(map[string]*lease),
+		seenImages: make(map[string]struct{}, 8192),
+		variants:   crawler.NewVariantImages(),
+		images:     make(map[string]ImageTask),
+		assignee:   make(map[string]string),
+		lastSeen:   make(map[string]time.Time),
+		stats:      stats.NewCollector(),
+		done:       make(chan struct{}),
+	}
+}
+
+// Done is closed once the frontier is empty and every lease was reported.
+func (c *Coordinator) Done() <-chan struct{} { return c.done }
+
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /lease", c.handleLease)
+	mux.HandleFunc("POST /report", c.handleReport)
+	mux.HandleFunc("GET /status", c.handleStatus)
+	return mux
+}
+
+// Run expires stale leases until the crawl is done or ctx is cancelled.
+func (c *Coordinator) Run(ctx context.Context) {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.finish()
+			return
+		case <-c.done:
+			return
+		case now := <-tick.C:
+			c.mu.Lock()
+			c.expireLocked(now)
+			c.checkLocked()
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *Coordinator) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.statusLocked()
+}
+
+func (c *Coordinator) handleLease(w http.ResponseWriter, r *http.Request) {
+	var req LeaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad lease request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Max <= 0 {
+		req.Max = 1
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.seenLocked(req.Node, now, req.Images)
+	c.expireLocked(now)
+	c.checkLocked()
+
+	resp := LeaseResponse{CrawlID: c.CrawlID}
+	select {
+	case <-c.done:
+		resp.Done = true
+	default:
+		for len(resp.Jobs) < req.Max && c.frontier.Len() > 0 && c.budget.PageAvailable() {
+			resp.Jobs = append(resp.Jobs, c.frontier.Pop())
+			c.budget.UsePage()
+		}
+		if len(c.requeued) > 0 {
+			c.status.Requeued += len(c.requeued)
+			resp.Images = c.assignLocked(req.Node, c.requeued)
+			c.requeued = nil
+		}
+	}
+
+	if len(resp.Jobs) > 0 {
+		c.nextID++
+		resp.LeaseID = fmt.Sprintf("%s-%d", req.Node, c.nextID)
+		resp.ExpiresAt = now.Add(c.leaseTTL)
+		c.leases[resp.LeaseID] = &lease{node: req.Node, jobs: resp.Jobs, expires: resp.ExpiresAt}
+		slog.Debug("lease", "lease", resp.LeaseID, "node", req.Node, "jobs", len(resp.Jobs), "queue", c.frontier.Len(), "leases", len(c.leases))
+	}
+
+	writeJSON(w, resp)
+}
+
+func (c *Coordinator) handleReport(w http.ResponseWriter, r *http.Request) {
+	var req ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad report: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the images were downloaded whether or not the lease is still valid
+	c.seenLocked(req.Node, time.Now(), req.Images)
+	if _, ok := c.leases[req.LeaseID]; !ok {
+		// The lease expired and its jobs were queued again; the node
+		// discards these results.
+		c.status.Late++
+		slog.Warn("late report rejected", "lease", req.LeaseID, "node", req.Node, "results", len(req.Results))
+		http.Error(w, "lease expired", http.StatusGone)
+		return
+	}
+	delete(c.leases, req.LeaseID)
+
+	var resp ReportResponse
+	for _, res := range req.Results {
+		c.budget.AddBytes(res.Bytes)
+		c.stats.PageLabel(res.URL, res.Bytes, time.Duration(res.FetchMs)*time.Millisecond, res.Status)
+		if res.Err != "" {
+			c.status.Failed++
+			slog.Warn("page failed", "url", res.URL, "host", crawler.HostOf(res.URL), "node", req.Node, "depth", res.Depth, "err", res.Err)
+			continue
+		}
+		if res.Skipped != "" {
+			c.status.Skipped++
+			c.stats.Skip(res.Skipped, 1)
+			slog.Info("page out of scope", "url", res.URL, "host", crawler.HostOf(res.URL), "node", req.Node, "reason", res.Skipped, "lang", res.Lang)
+			// the pages it links to take its place, e.g. the preferred variant
+			if !c.draining {
+				for _, link := range res.Links {
+					c.frontier.Add(link.URL, res.Depth, res.Distance)
+				}
+			}
+			continue
+		}
+		c.status.Pages++
+		if c.Hreflang != "" {
+			c.frontier.Exclude("variant", crawler.OtherVariants(res.URL, res.Alternates)...)
+		}
+		slog.Info("page fetched", "url", res.URL, "host", crawler.HostOf(res.URL), "node", req.Node, "depth", res.Depth, "links", len(res.Links), "images", len(res.Images))
+
+		c.applyBudgetLocked()
+		for _, img := range crawler.UniqueImages(res.Images) {
+			if c.draining || !c.budget.ImageAvailable() {
+				break
+			}
+			if _, ok := c.seenImages[img.URL]; ok {
+				continue
+			}
+			c.seenImages[img.URL] = struct{}{}
+			if res.Variant != "" && c.variants.Seen(res.Variant, res.URL, res.Alternates, img.URL) {
+				continue
+			}
+			c.budget.UseImage()
+			c.status.Images++
+			resp.Images = append(resp.Images, ImageTask{PageURL: res.URL, Ref: img})
+		}
+		if res.Depth > 0 && !c.draining {
+			for _, link := range crawler.UniqueLinks(res.Links) {
+				c.frontier.Add(link.URL, res.Depth-1, res.Distance+1)
+			}
+		}
+	}
+	resp.Images = c.assignLocked(req.Node, resp.Images)
+	c.checkLocked()
+
+	writeJSON(w, resp)
+}
+
+func (c *Coordinator) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, c.Status())
+}
+
+// expireLocked returns the jobs of expired leases to the queue and their
+// pages to the budget.
+func (c *Coordinator) expireLocked(now time.Time) {
+	for id, l := range c.leases {
+		if now.Before(l.expires) {
+			continue
+		}
+		delete(c.leases, id)
+		c.status.Expired++
+		c.budget.ReturnPages(len(l.jobs))
+		c.frontier.Requeue(l.jobs)
+		slog.Warn("lease expired", "lease", id, "node", l.node, "requeued", len(l.jobs))
+	}
+
+	silent := make(map[string]int)
+	for id, node := range c.assignee {
+		if now.Sub(c.lastSeen[node]) < c.leaseTTL {
+			continue
+		}
+		silent[node]++
+		c.requeued = append(c.requeued, c.images[id])
+		delete(c.images, id)
+		delete(c.assignee, id)
+	}
+	for node, n := range silent {
+		slog.Warn("node silent, images requeued", "node", node, "images", n)
+	}
+}
+
+// seenLocked notes that node is alive, which keeps its image assignments,
+// and records the image tasks it finished.
+func (c *Coordinator) seenLocked(node string, now time.Time, done []ImageDone) {
+	c.lastSeen[node] = now
+	for _, d := range done {
+		c.budget.AddBytes(d.Bytes)
+		if c.assignee[d.ID] != node {
+			// requeued while the node was silent; the next assignee
+			// reports it
+			continue
+		}
+		delete(c.images, d.ID)
+		delete(c.assignee, d.ID)
+		c.stats.ImageLabel(d.Format, d.Bytes, d.Err)
+	}
+}
+
+// assignLocked gives tasks IDs where they have none and assigns them to
+// node.
+func (c *Coordinator) assignLocked(node string, tasks []ImageTask) []ImageTask {
+	for i := range tasks {
+		if tasks[i].ID == "" {
+			c.nextImage++
+			tasks[i].ID = fmt.Sprintf("img-%d", c.nextImage)
+		}
+		c.images[tasks[i].ID] = tasks[i]
+		c.assignee[tasks[i].ID] = node
+	}
+	return tasks
+}
+
+// checkLocked applies budgets and detects the end of the crawl: no pages
+// queued or leased and no images assigned or waiting for a node.
+func (c *Coordinator) checkLocked() {
+	c.applyBudgetLocked()
+	if c.frontier.Len() == 0 && len(c.leases) == 0 && len(c.images) == 0 && len(c.requeued) == 0 {
+		c.finish()
+	}
+}
+
+func (c *Coordinator) applyBudgetLocked() {
+	if !c.draining {
+		if reason := c.budget.Exhausted(); reason != "" {
+			c.draining = true
+			slog.Info("budget exhausted, draining", "reason", reason, "dropped_pages", c.frontier.Drop(), "leases", len(c.leases))
+		}
+	}
+	if c.frontier.Len() > 0 && !c.budget.PageAvailable() {
+		slog.Info("budget exhausted", "reason", "max-pages", "dropped_pages", c.frontier.Drop())
+	}
+}
+
+func (c *Coordinator) finish() {
+	c.doneOnce.Do(func() { close(c.done) })
+}
+
+func (c *Coordinator) statusLocked() Status {
+	s := c.status
+	s.Queue = c.frontier.Len()
+	s.Visited = c.frontier.Visited()
+	s.Leases = len(c.leases)
+	for _, l := range c.leases {
+		s.InFlight += len(l.jobs)
+	}
+	s.Assigned = len(c.images) + len(c.requeued)
+	s.Draining = c.draining
+	select {
+	case <-c.done:
+		s.Done = true
+	default:
+	}
+	return s
+}
+
+// Report builds the statistics report of the crawl from the pages and
+// images the nodes reported. Call it once, after Run returned.
+func (c *Coordinator) Report(seed, outcome string) *stats.Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for reason, n := range c.frontier.Skipped() {
+		c.stats.Skip(reason, n)
+	}
+	inFlight := 0
+	for _, l := range c.leases {
+		inFlight += len(l.jobs)
+	}
+	return c.stats.Finish(seed, outcome, stats.Unfinished{
+		Pages:  c.frontier.Len() + inFlight,
+		Images: len(c.images) + len(c.requeued),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
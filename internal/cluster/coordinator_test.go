@@ -0,0 +1,170 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/stats"
+)
+
+func TestLateReportRejected(t *testing.T) {
+	const seed = "https://example.com/"
+	budget := &crawler.Budget{MaxPages: 1}
+	budget.Start()
+	frontier := crawler.NewFrontier(seed, crawler.CrawlJob{}, nil, budget)
+	frontier.Add(seed, 1, 0)
+
+	coord := NewCoordinator(frontier, budget, time.Hour)
+	srv := httptest.NewServer(coord.Handler())
+	defer srv.Close()
+	ctx := context.Background()
+	slow := NewClient(srv.URL, "slow")
+	fast := NewClient(srv.URL, "fast")
+
+	first, err := slow.Lease(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Jobs) != 1 {
+		t.Fatalf("first lease: got %d jobs, want 1", len(first.Jobs))
+	}
+
+	// expire the lease: its job is queued again and the page returned to
+	// the budget, so it can be leased although max-pages is 1
+	coord.mu.Lock()
+	coord.expireLocked(time.Now().Add(2 * time.Hour))
+	coord.mu.Unlock()
+	if pages, _, _, _ := budget.Usage(); pages != 0 {
+		t.Fatalf("pages used after expiry: got %d, want 0", pages)
+	}
+	second, err := fast.Lease(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Jobs) != 1 || second.Jobs[0].URL != first.Jobs[0].URL {
+		t.Fatalf("second lease: got %+v, want the requeued job", second.Jobs)
+	}
+
+	page := Result{URL: seed, Depth: 1, Images: []crawler.ImageRef{{URL: seed + "a.png"}}}
+	_, err = slow.Report(ctx, ReportRequest{LeaseID: first.LeaseID, Results: []Result{page}})
+	if !errors.Is(err, ErrLeaseExpired) {
+		t.Fatalf("late report: got err %v, want ErrLeaseExpired", err)
+	}
+	resp, err := fast.Report(ctx, ReportRequest{LeaseID: second.LeaseID, Results: []Result{page}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Images) != 1 {
+		t.Errorf("images assigned to the valid report: got %d, want 1", len(resp.Images))
+	}
+
+	st := coord.Status()
+	if st.Pages != 1 || st.Expired != 1 || st.Late != 1 {
+		t.Errorf("status: got pages=%d expired=%d late=%d, want 1, 1, 1", st.Pages, st.Expired, st.Late)
+	}
+	if pages, _, _, _ := budget.Usage(); pages != 1 {
+		t.Errorf("pages used: got %d, want 1", pages)
+	}
+	select {
+	case <-coord.Done():
+		t.Fatal("coordinator done while the image was still assigned")
+	default:
+	}
+	done := []ImageDone{{ID: resp.Images[0].ID, Format: "png", Bytes: 10}}
+	if _, err := fast.Lease(ctx, 10, done); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-coord.Done():
+	default:
+		t.Error("coordinator not done after the only page and image were reported")
+	}
+}
+
+// TestSilentNodeImagesRequeued checks that the images of a node that stops
+// leasing and reporting go to the next node that leases, and that the
+// report counts each page and image once.
+func TestSilentNodeImagesRequeued(t *testing.T) {
+	const seed = "https://example.com/"
+	budget := &crawler.Budget{}
+	budget.Start()
+	frontier := crawler.NewFrontier(seed, crawler.CrawlJob{}, nil, budget)
+	frontier.Add(seed, 0, 0)
+
+	ttl := time.Minute
+	coord := NewCoordinator(frontier, budget, ttl)
+	srv := httptest.NewServer(coord.Handler())
+	defer srv.Close()
+	ctx := context.Background()
+	dead := NewClient(srv.URL, "dead")
+	alive := NewClient(srv.URL, "alive")
+
+	lease, err := dead.Lease(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := Result{URL: seed, Bytes: 100, FetchMs: 20, Images: []crawler.ImageRef{{URL: seed + "a.png"}, {URL: seed + "b.png"}}}
+	report, err := dead.Report(ctx, ReportRequest{LeaseID: lease.LeaseID, Results: []Result{page}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Images) != 2 || report.Images[0].ID == "" || report.Images[0].ID == report.Images[1].ID {
+		t.Fatalf("assigned images %+v, want two with distinct IDs", report.Images)
+	}
+	// the node finishes one image, then goes silent
+	first := ImageDone{ID: report.Images[0].ID, Format: "png", Bytes: 1000}
+	if _, err := dead.Lease(ctx, 10, []ImageDone{first}); err != nil {
+		t.Fatal(err)
+	}
+
+	// while the node is within the TTL its image stays with it
+	coord.mu.Lock()
+	coord.expireLocked(time.Now().Add(ttl / 2))
+	coord.mu.Unlock()
+	if got, err := alive.Lease(ctx, 10, nil); err != nil || len(got.Images) != 0 {
+		t.Fatalf("lease while the node is alive: images %+v, err %v, want none", got.Images, err)
+	}
+
+	coord.mu.Lock()
+	coord.expireLocked(time.Now().Add(2 * ttl))
+	coord.mu.Unlock()
+	taken, err := alive.Lease(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(taken.Images) != 1 || taken.Images[0].ID != report.Images[1].ID {
+		t.Fatalf("lease after the node went silent: images %+v, want %s", taken.Images, report.Images[1].ID)
+	}
+	if st := coord.Status(); st.Requeued != 1 || st.Assigned != 1 || st.Done {
+		t.Errorf("status %+v, want 1 requeued, 1 assigned, not done", st)
+	}
+
+	// the silent node's late outcome is not counted, the new assignee's is
+	late := ImageDone{ID: report.Images[1].ID, Format: "png", Bytes: 2000}
+	if _, err := dead.Lease(ctx, 10, []ImageDone{late}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alive.Lease(ctx, 10, []ImageDone{{ID: taken.Images[0].ID, Err: "404"}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-coord.Done():
+	default:
+		t.Fatal("coordinator not done after every image was reported")
+	}
+
+	r := coord.Report(seed, "complete")
+	if r.Pages.OK != 1 || r.Images.OK != 1 || r.Images.Failed != 1 || r.ImageErrors["404"] != 1 {
+		t.Errorf("report pages %+v, images %+v, errors %v, want 1 page, 1 image ok and 1 failed with 404", r.Pages, r.Images, r.ImageErrors)
+	}
+	if r.Bytes.Images != 1000 || r.Latency.Max != 20 || r.Unfinished != (stats.Unfinished{}) {
+		t.Errorf("report bytes %+v, latency %+v, unfinished %+v, want 1000 image bytes, 20ms and nothing left", r.Bytes, r.Latency, r.Unfinished)
+	}
+	if _, _, bytes, _ := budget.Usage(); bytes != 3100 {
+		t.Errorf("budget bytes %d, want 3100: every download counts", bytes)
+	}
+}
@@ -0,0 +1,112 @@
+package cluster
+
+import (
+	"errors"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+)
+
+// Messages exchanged between the coordinator and crawler nodes as JSON over HTTP.
+
+type LeaseRequest struct {
+	Node   string      `json:"node"`
+	Max    int         `json:"max"`
+	Images []ImageDone `json:"images,omitempty"` // image tasks finished since the last lease or report
+}
+
+type LeaseResponse struct {
+	LeaseID   string             `json:"lease_id,omitempty"`
+	Jobs      []crawler.CrawlJob `json:"jobs,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at,omitzero"`
+	Done      bool               `json:"done"` // crawl finished; the node should exit
+	CrawlID   int64              `json:"crawl_id,omitempty"`
+	Images    []ImageTask        `json:"images,omitempty"` // assigned to another node that went silent
+}
+
+// Result is a crawler.CrawlResult with the error flattened to a string.
+// The page text stays with the node that stores it.
+type Result struct {
+	URL        string              `json:"url"`
+	Title      string              `json:"title,omitempty"`
+	Lang       string              `json:"lang,omitempty"`
+	Alternates []crawler.Alternate `json:"alternates,omitempty"`
+	Variant    string              `json:"variant,omitempty"`
+	Links      []crawler.Link      `json:"links,omitempty"`
+	Images     []crawler.ImageRef  `json:"images,omitempty"`
+	Depth      int                 `json:"depth"`
+	Distance   int                 `json:"distance"`
+	Bytes      int64               `json:"bytes"`
+	FetchMs    int64               `json:"fetch_ms"`
+	Skipped    string              `json:"skipped,omitempty"`
+	Err        string              `json:"err,omitempty"`
+	Status     string              `json:"status,omitempty"` // fetch.Label of the error
+}
+
+func NewResult(r crawler.CrawlResult) Result {
+	out := Result{URL: r.URL, Title: r.Title, Lang: r.Lang, Alternates: r.Alternates, Variant: r.Variant, Links: r.Links, Images: r.Images, Depth: r.Depth, Distance: r.Distance, Bytes: r.Bytes, FetchMs: r.Fetch.Milliseconds(), Skipped: r.Skipped}
+	if r.Err != nil {
+		out.Err = r.Err.Error()
+		out.Status = fetch.Label(r.Err)
+	}
+	return out
+}
+
+func (r Result) CrawlResult() crawler.CrawlResult {
+	out := crawler.CrawlResult{URL: r.URL, Title: r.Title, Lang: r.Lang, Alternates: r.Alternates, Variant: r.Variant, Links: r.Links, Images: r.Images, Depth: r.Depth, Distance: r.Distance, Bytes: r.Bytes, Fetch: time.Duration(r.FetchMs) * time.Millisecond, Skipped: r.Skipped}
+	if r.Err != "" {
+		out.Err = errors.New(r.Err)
+	}
+	return out
+}
+
+// ErrLeaseExpired is returned by Client.Report when the coordinator
+// rejected the report because its lease had expired and the jobs were
+// queued again.
+var ErrLeaseExpired = errors.New("lease expired")
+
+type ReportRequest struct {
+	Node    string      `json:"node"`
+	LeaseID string      `json:"lease_id"`
+	Results []Result    `json:"results"`
+	Images  []ImageDone `json:"images,omitempty"` // image tasks finished since the last lease or report
+}
+
+// ImageTask is an image the coordinator assigns to a node. The assignment
+// holds while the node keeps leasing or reporting; after --lease-ttl of
+// silence it goes to another node.
+type ImageTask struct {
+	ID      string           `json:"id"`
+	PageURL string           `json:"page_url"`
+	Ref     crawler.ImageRef `json:"ref"`
+}
+
+// ImageDone is the outcome of an ImageTask.
+type ImageDone struct {
+	ID     string `json:"id"`
+	Format string `json:"format,omitempty"`
+	Bytes  int64  `json:"bytes"`
+	Err    string `json:"err,omitempty"` // fetch.Label of the error
+}
+
+type ReportResponse struct {
+	Images []ImageTask `json:"images,omitempty"`
+}
+
+type Status struct {
+	Queue    int  `json:"queue"`
+	Visited  int  `json:"visited"`
+	Leases   int  `json:"leases"`
+	InFlight int  `json:"in_flight"`
+	Pages    int  `json:"pages"`
+	Failed   int  `json:"failed"`
+	Images   int  `json:"images"`
+	Assigned int  `json:"images_assigned"` // images handed out and not reported done
+	Skipped  int  `json:"skipped"`         // pages fetched but out of scope, e.g. in another language
+	Expired  int  `json:"expired"`
+	Late     int  `json:"late_reports"`    // reports for expired leases, rejected
+	Requeued int  `json:"images_requeued"` // image assignments of silent nodes handed out again
+	Draining bool `json:"draining"`
+	Done     bool `json:"done"`
+}
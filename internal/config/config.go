@@ -0,0 +1,682 @@
+// Package config loads the configuration file (JSON, YAML or TOML) shared
+// by the crawler and the web server. Environment variables override the
+// file and command-line flags override both.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoCrawler/internal/cron"
+	"GoCrawler/internal/selector"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFile names the config file when no --config flag is given.
+const EnvFile = "GOCRAWLER_CONFIG"
+
+type Config struct {
+	Database Database        `json:"database"`
+	Dirs     Dirs            `json:"dirs"`
+	Workers  Workers         `json:"workers"`
+	Fetch    Fetch           `json:"fetch"`
+	Hosts    map[string]Host `json:"hosts"`
+	Extract  []ExtractRule   `json:"extract"`
+	Daemon   Daemon          `json:"daemon"`
+}
+
+type Database struct {
+	// DSN is a go-sql-driver/mysql data source name, e.g.
+	// "crawler:secret@tcp(localhost:3306)/crawlerdb".
+	DSN string `json:"dsn"`
+}
+
+// Dirs are where downloaded images and their thumbnails are written and
+// served from.
+type Dirs struct {
+	Images     string `json:"images"`
+	Thumbnails string `json:"thumbnails"`
+}
+
+type Workers struct {
+	Pages         int      `json:"pages"`
+	Images        int      `json:"images"`
+	ImageTimeout  Duration `json:"image_timeout"` // download, decode and thumbnail one image
+	MaxGoroutines int      `json:"max_goroutines"`
+
+	HostConcurrency HostConcurrency `json:"host_concurrency"`
+}
+
+// HostConcurrency bounds the pages fetched at once from one host. Without
+// Adaptive only Pages bounds a host; with it the limit starts at Initial and
+// moves between Min and Max as the host responds (see crawler.HostLimiter).
+type HostConcurrency struct {
+	Adaptive bool `json:"adaptive"`
+	Min      int  `json:"min"`
+	Max      int  `json:"max"` // 0: workers.pages
+	Initial  int  `json:"initial"`
+}
+
+type Fetch struct {
+	UserAgent    string            `json:"user_agent"`
+	PageTimeout  Duration          `json:"page_timeout"`
+	ImageTimeout Duration          `json:"image_timeout"`
+	Delay        Duration          `json:"delay"` // between requests to the same host
+	Headers      map[string]string `json:"headers"`
+}
+
+// Host overrides fetch settings for one host and its subdomains. Unset
+// fields keep the global value.
+type Host struct {
+	JS      *bool             `json:"js"`
+	Delay   *Duration         `json:"delay"`
+	Headers map[string]string `json:"headers"` // added to the global headers
+}
+
+// ExtractRule scrapes fields with CSS selectors from the pages whose URL
+// matches the regular expression URL. The values are stored as page fields
+// under the map keys.
+type ExtractRule struct {
+	URL    string           `json:"url"`
+	Fields map[string]Field `json:"fields"`
+}
+
+// Field selects the text of the first element matching Selector, or its
+// attribute Attr when set; with All, of every matching element.
+type Field struct {
+	Selector string `json:"selector"`
+	Attr     string `json:"attr"`
+	All      bool   `json:"all"`
+}
+
+// Daemon configures `crawler daemon`, which runs the crawls listed here on
+// their schedules.
+type Daemon struct {
+	Listen        string     `json:"listen"`         // status endpoint address
+	MaxConcurrent int        `json:"max_concurrent"` // crawls running at once
+	RunsDir       string     `json:"runs_dir"`       // per-run logs, stats reports and history
+	Crawls        []Schedule `json:"crawls"`
+}
+
+// Schedule is one recurring crawl. Args are crawler flags, e.g.
+// ["--url", "https://example.com", "--depth", "3"]; the daemon adds
+// --config, --name and --stats itself.
+type Schedule struct {
+	Name string   `json:"name"`
+	Cron string   `json:"cron"`
+	Args []string `json:"args"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("1.5s",
+// "250ms") or a number of seconds.
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	v, err := parseDuration(b)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func parseDuration(b []byte) (Duration, error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q (want e.g. \"500ms\" or \"2s\")", s)
+		}
+		return Duration(v), nil
+	}
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return 0, fmt.Errorf("invalid duration %s (want a string like \"2s\" or a number of seconds)", b)
+	}
+	return Duration(secs * float64(time.Second)), nil
+}
+
+// Default returns the settings used when neither a config file nor the
+// environment says otherwise. There is no default DSN.
+func Default() *Config {
+	return &Config{
+		Dirs: Dirs{Images: "images", Thumbnails: "thumbnails"},
+		Workers: Workers{
+			Pages:         10,
+			Images:        4,
+			ImageTimeout:  Duration(20 * time.Second),
+			MaxGoroutines: 200,
+
+			HostConcurrency: HostConcurrency{Min: 1, Initial: 2},
+		},
+		Fetch: Fetch{
+			UserAgent:    "GoCrawler/1.0 (+github.com/you)",
+			PageTimeout:  Duration(10 * time.Second),
+			ImageTimeout: Duration(15 * time.Second),
+		},
+		Daemon: Daemon{Listen: ":9300", MaxConcurrent: 1, RunsDir: "runs"},
+	}
+}
+
+// KeyError is a problem with one setting. Key is the dotted path in the
+// config file, e.g. "hosts.example.com.delay".
+type KeyError struct {
+	Source string // file name or environment variable
+	Key    string
+	Err    error
+}
+
+func (e *KeyError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Source, e.Key, e.Err)
+}
+
+func (e *KeyError) Unwrap() error { return e.Err }
+
+// Load reads the config file at path (or $GOCRAWLER_CONFIG when path is
+// empty; neither is required), applies environment overrides and
+// validates the result. Files ending in .yaml, .yml or .toml are read as
+// YAML or TOML, anything else as JSON; the keys are the same.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvFile)
+	}
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = toJSON(path, data); err != nil {
+			return nil, err
+		}
+		if err := cfg.decode(path, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// toJSON converts a YAML or TOML document, chosen by the extension of
+// path, into the equivalent JSON, so that every format is checked and
+// decoded the same way. JSON is returned as it is.
+func toJSON(path string, data []byte) ([]byte, error) {
+	var raw any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return data, nil
+	}
+	out, err := json.Marshal(jsonValue(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+// jsonValue turns YAML mappings with non-string keys into JSON objects.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonValue(e)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return out
+	case []any:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+		return v
+	case []map[string]any: // TOML arrays of tables
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = jsonValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// decode merges the JSON document data into c. Unknown keys and malformed
+// values are reported with their path.
+func (c *Config) decode(path string, data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			line, col := position(data, se.Offset)
+			return fmt.Errorf("%s:%d:%d: %v", path, line, col, err)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := check("", raw, reflect.TypeFor[Config]()); err != nil {
+		err.Source = path
+		return err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			return &KeyError{Source: path, Key: te.Field, Err: fmt.Errorf("expected %s, got %s", te.Type, te.Value)}
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeFor[Duration]()
+
+// check walks the decoded JSON value v alongside the Go type t, rejecting
+// keys t has no field for and durations that do not parse. Everything else
+// is left to json.Unmarshal.
+func check(key string, v any, t reflect.Type) *KeyError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == durationType && v != nil {
+		b, _ := json.Marshal(v)
+		if _, err := parseDuration(b); err != nil {
+			return &KeyError{Key: key, Err: err}
+		}
+		return nil
+	}
+
+	if arr, ok := v.([]any); ok && t.Kind() == reflect.Slice {
+		for i, e := range arr {
+			if err := check(fmt.Sprintf("%s[%d]", key, i), e, t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			fields[name] = f.Type
+		}
+		for _, k := range slices.Sorted(maps.Keys(obj)) {
+			ft, ok := fields[k]
+			if !ok {
+				return &KeyError{Key: join(key, k), Err: errors.New("unknown key")}
+			}
+			if err := check(join(key, k), obj[k], ft); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range slices.Sorted(maps.Keys(obj)) {
+			if err := check(join(key, k), obj[k], t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func join(key, k string) string {
+	if key == "" {
+		return k
+	}
+	return key + "." + k
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (line, col int) {
+	before := data[:min(int(offset), len(data))]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// envVars maps environment variables onto settings.
+var envVars = []struct {
+	name string
+	key  string
+	set  func(c *Config, v string) error
+}{
+	{"GOCRAWLER_DSN", "database.dsn", func(c *Config, v string) error { c.Database.DSN = v; return nil }},
+	{"GOCRAWLER_IMAGE_DIR", "dirs.images", func(c *Config, v string) error { c.Dirs.Images = v; return nil }},
+	{"GOCRAWLER_THUMBNAIL_DIR", "dirs.thumbnails", func(c *Config, v string) error { c.Dirs.Thumbnails = v; return nil }},
+	{"GOCRAWLER_WORKERS", "workers.pages", func(c *Config, v string) error { return setInt(&c.Workers.Pages, v) }},
+	{"GOCRAWLER_IMG_WORKERS", "workers.images", func(c *Config, v string) error { return setInt(&c.Workers.Images, v) }},
+	{"GOCRAWLER_IMG_TIMEOUT", "workers.image_timeout", func(c *Config, v string) error { return setDuration(&c.Workers.ImageTimeout, v) }},
+	{"GOCRAWLER_MAX_GOROUTINES", "workers.max_goroutines", func(c *Config, v string) error { return setInt(&c.Workers.MaxGoroutines, v) }},
+	{"GOCRAWLER_ADAPTIVE", "workers.host_concurrency.adaptive", func(c *Config, v string) error { return setBool(&c.Workers.HostConcurrency.Adaptive, v) }},
+	{"GOCRAWLER_HOST_MIN", "workers.host_concurrency.min", func(c *Config, v string) error { return setInt(&c.Workers.HostConcurrency.Min, v) }},
+	{"GOCRAWLER_HOST_MAX", "workers.host_concurrency.max", func(c *Config, v string) error { return setInt(&c.Workers.HostConcurrency.Max, v) }},
+	{"GOCRAWLER_HOST_INITIAL", "workers.host_concurrency.initial", func(c *Config, v string) error { return setInt(&c.Workers.HostConcurrency.Initial, v) }},
+	{"GOCRAWLER_USER_AGENT", "fetch.user_agent", func(c *Config, v string) error { c.Fetch.UserAgent = v; return nil }},
+	{"GOCRAWLER_PAGE_TIMEOUT", "fetch.page_timeout", func(c *Config, v string) error { return setDuration(&c.Fetch.PageTimeout, v) }},
+	{"GOCRAWLER_IMAGE_FETCH_TIMEOUT", "fetch.image_timeout", func(c *Config, v string) error { return setDuration(&c.Fetch.ImageTimeout, v) }},
+	{"GOCRAWLER_DELAY", "fetch.delay", func(c *Config, v string) error { return setDuration(&c.Fetch.Delay, v) }},
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, e := range envVars {
+		v, ok := lookup(e.name)
+		if !ok {
+			continue
+		}
+		if err := e.set(c, v); err != nil {
+			return &KeyError{Source: e.name, Key: e.key, Err: err}
+		}
+	}
+	return nil
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", v)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q (want true or false)", v)
+	}
+	*dst = b
+	return nil
+}
+
+func setDuration(dst *Duration, v string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (want e.g. \"500ms\" or \"2s\")", v)
+	}
+	*dst = Duration(d)
+	return nil
+}
+
+// Validate reports the first invalid setting.
+func (c *Config) Validate() error {
+	bad := func(key, format string, args ...any) error {
+		return &KeyError{Key: key, Err: fmt.Errorf(format, args...)}
+	}
+
+	if c.Database.DSN != "" {
+		if _, err := mysql.ParseDSN(c.Database.DSN); err != nil {
+			return bad("database.dsn", "%v", err)
+		}
+	}
+	if c.Dirs.Images == "" {
+		return bad("dirs.images", "must not be empty")
+	}
+	if c.Dirs.Thumbnails == "" {
+		return bad("dirs.thumbnails", "must not be empty")
+	}
+	if c.Workers.Pages < 1 {
+		return bad("workers.pages", "must be at least 1, got %d", c.Workers.Pages)
+	}
+	if c.Workers.Images < 1 {
+		return bad("workers.images", "must be at least 1, got %d", c.Workers.Images)
+	}
+	if c.Workers.ImageTimeout < Duration(time.Second) {
+		return bad("workers.image_timeout", "must be at least 1s, got %s", c.Workers.ImageTimeout.Std())
+	}
+	if c.Workers.MaxGoroutines < 1 {
+		return bad("workers.max_goroutines", "must be at least 1, got %d", c.Workers.MaxGoroutines)
+	}
+	hc := c.Workers.HostConcurrency
+	if hc.Min < 1 {
+		return bad("workers.host_concurrency.min", "must be at least 1, got %d", hc.Min)
+	}
+	if hc.Min > c.Workers.Pages {
+		return bad("workers.host_concurrency.min", "must not exceed workers.pages (%d), got %d", c.Workers.Pages, hc.Min)
+	}
+	if hc.Max != 0 && hc.Max < hc.Min {
+		return bad("workers.host_concurrency.max", "must be 0 or at least min (%d), got %d", hc.Min, hc.Max)
+	}
+	if hc.Initial < hc.Min || hc.Max != 0 && hc.Initial > hc.Max {
+		return bad("workers.host_concurrency.initial", "must be between min and max, got %d", hc.Initial)
+	}
+	if c.Fetch.PageTimeout <= 0 {
+		return bad("fetch.page_timeout", "must be positive, got %s", c.Fetch.PageTimeout.Std())
+	}
+	if c.Fetch.ImageTimeout <= 0 {
+		return bad("fetch.image_timeout", "must be positive, got %s", c.Fetch.ImageTimeout.Std())
+	}
+	if c.Fetch.Delay < 0 {
+		return bad("fetch.delay", "must not be negative, got %s", c.Fetch.Delay.Std())
+	}
+	if err := checkHeaders("fetch.headers", c.Fetch.Headers); err != nil {
+		return err
+	}
+
+	for _, host := range slices.Sorted(maps.Keys(c.Hosts)) {
+		h := c.Hosts[host]
+		key := "hosts." + host
+		if err := checkHost(host); err != nil {
+			return &KeyError{Key: key, Err: err}
+		}
+		if h.Delay != nil && *h.Delay < 0 {
+			return bad(key+".delay", "must not be negative, got %s", h.Delay.Std())
+		}
+		if err := checkHeaders(key+".headers", h.Headers); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Extract {
+		key := fmt.Sprintf("extract[%d]", i)
+		if r.URL == "" {
+			return bad(key+".url", "must not be empty (use \".\" to match every page)")
+		}
+		if _, err := regexp.Compile(r.URL); err != nil {
+			return bad(key+".url", "%v", err)
+		}
+		if len(r.Fields) == 0 {
+			return bad(key+".fields", "must not be empty")
+		}
+		for _, name := range slices.Sorted(maps.Keys(r.Fields)) {
+			if name == "" || len(name) > 255 {
+				return bad(key+".fields", "field names must be 1 to 255 characters, got %q", name)
+			}
+			if _, err := selector.Compile(r.Fields[name].Selector); err != nil {
+				return bad(key+".fields."+name+".selector", "%v", err)
+			}
+		}
+	}
+	return c.Daemon.validate()
+}
+
+// reservedArgs are set by the daemon for every run.
+var reservedArgs = []string{"config", "name", "stats"}
+
+func (d *Daemon) validate() error {
+	if d.MaxConcurrent < 1 {
+		return &KeyError{Key: "daemon.max_concurrent", Err: fmt.Errorf("must be at least 1, got %d", d.MaxConcurrent)}
+	}
+	if d.RunsDir == "" {
+		return &KeyError{Key: "daemon.runs_dir", Err: errors.New("must not be empty")}
+	}
+	seen := make(map[string]bool, len(d.Crawls))
+	for i, s := range d.Crawls {
+		key := fmt.Sprintf("daemon.crawls[%d]", i)
+		switch {
+		case s.Name == "":
+			return &KeyError{Key: key + ".name", Err: errors.New("must not be empty")}
+		case strings.Trim(s.Name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._-") != "" || s.Name[0] == '.':
+			return &KeyError{Key: key + ".name", Err: fmt.Errorf("%q: use letters, digits, '.', '_' and '-' (it names the run files)", s.Name)}
+		case seen[s.Name]:
+			return &KeyError{Key: key + ".name", Err: fmt.Errorf("duplicate name %q", s.Name)}
+		}
+		seen[s.Name] = true
+		if _, err := cron.Parse(s.Cron); err != nil {
+			return &KeyError{Key: key + ".cron", Err: err}
+		}
+		hasURL := false
+		for _, a := range s.Args {
+			name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+			if !strings.HasPrefix(a, "-") {
+				continue
+			}
+			if slices.Contains(reservedArgs, name) {
+				return &KeyError{Key: key + ".args", Err: fmt.Errorf("%s is set by the daemon", a)}
+			}
+			hasURL = hasURL || name == "url"
+		}
+		if !hasURL {
+			return &KeyError{Key: key + ".args", Err: errors.New("missing --url")}
+		}
+	}
+	return nil
+}
+
+// checkHost accepts a bare host name such as "example.com" or
+// "cdn.example.com".
+func checkHost(host string) error {
+	if host == "" {
+		return errors.New("host name must not be empty")
+	}
+	if strings.ContainsAny(host, "/:@ ") {
+		return fmt.Errorf("want a bare host name like \"example.com\", got %q", host)
+	}
+	if u, err := url.Parse("http://" + host); err != nil || u.Hostname() != host {
+		return fmt.Errorf("invalid host name %q", host)
+	}
+	return nil
+}
+
+func checkHeaders(key string, headers map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		if !validHeaderName(name) {
+			return &KeyError{Key: key + "." + name, Err: errors.New("invalid header name")}
+		}
+		if strings.ContainsAny(headers[name], "\r\n\x00") {
+			return &KeyError{Key: key + "." + name, Err: errors.New("invalid header value")}
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an RFC 7230 token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// host returns the override block for hostname: the longest configured
+// name equal to it or one of its parent domains.
+func (c *Config) host(hostname string) (Host, bool) {
+	hostname = strings.ToLower(hostname)
+	best, found := "", false
+	for name := range c.Hosts {
+		n := strings.ToLower(name)
+		if (hostname == n || strings.HasSuffix(hostname, "."+n)) && len(n) > len(best) {
+			best, found = name, true
+		}
+	}
+	return c.Hosts[best], found
+}
+
+// UseJS reports whether pages of rawURL should be rendered with the
+// headless browser, given the global setting def.
+func (c *Config) UseJS(rawURL string, def bool) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return def
+	}
+	if h, ok := c.host(u.Hostname()); ok && h.JS != nil {
+		return *h.JS
+	}
+	return def
+}
+
+// WantsJS reports whether any host override turns JS rendering on, i.e.
+// whether a browser is needed even without --js.
+func (c *Config) WantsJS() bool {
+	for _, h := range c.Hosts {
+		if h.JS != nil && *h.JS {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay is the minimum time between requests to hostname.
+func (c *Config) Delay(hostname string) time.Duration {
+	if h, ok := c.host(hostname); ok && h.Delay != nil {
+		return h.Delay.Std()
+	}
+	return c.Fetch.Delay.Std()
+}
+
+// Headers are the extra request headers for hostname: the global headers
+// overlaid with the host's own.
+func (c *Config) Headers(hostname string) map[string]string {
+	h, _ := c.host(hostname)
+	if len(c.Fetch.Headers) == 0 && len(h.Headers) == 0 {
+		return nil
+	}
+	out := maps.Clone(c.Fetch.Headers)
+	if out == nil {
+		out = make(map[string]string, len(h.Headers))
+	}
+	maps.Copy(out, h.Headers)
+	return out
+}
+
+// HasHostRules reports whether requests need the per-host transport at all.
+func (c *Config) HasHostRules() bool {
+	return c.Fetch.Delay > 0 || len(c.Fetch.Headers) > 0 || len(c.Hosts) > 0
+}
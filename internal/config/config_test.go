@@ -0,0 +1,326 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{
+  "database": {"dsn": "u:p@tcp(db:3306)/crawl"},
+  "workers": {"pages": 4, "image_timeout": 30},
+  "fetch": {"delay": "500ms", "headers": {"Accept-Language": "de"}},
+  "hosts": {
+    "app.example.com": {"js": true},
+    "example.org": {"delay": "2s", "headers": {"Cookie": "consent=1"}}
+  },
+  "extract": [{"url": "^https://shop\\.example\\.com/", "fields": {"price": {"selector": "span.price", "attr": "data-amount"}}}],
+  "daemon": {"crawls": [{"name": "nightly", "cron": "30 2 * * *", "args": ["--url", "https://example.com"]}]}
+}`,
+		"config.yaml": `
+database: {dsn: "u:p@tcp(db:3306)/crawl"}
+workers:
+  pages: 4
+  image_timeout: 30
+fetch:
+  delay: 500ms
+  headers: {Accept-Language: de}
+hosts:
+  app.example.com: {js: true}
+  example.org:
+    delay: 2s
+    headers: {Cookie: consent=1}
+extract:
+  - url: ^https://shop\.example\.com/
+    fields:
+      price: {selector: span.price, attr: data-amount}
+daemon:
+  crawls:
+    - name: nightly
+      cron: "30 2 * * *"
+      args: [--url, "https://example.com"]
+`,
+		"config.toml": `
+[database]
+dsn = "u:p@tcp(db:3306)/crawl"
+
+[workers]
+pages = 4
+image_timeout = 30
+
+[fetch]
+delay = "500ms"
+headers = { Accept-Language = "de" }
+
+[hosts."app.example.com"]
+js = true
+
+[hosts."example.org"]
+delay = "2s"
+headers = { Cookie = "consent=1" }
+
+[[extract]]
+url = '^https://shop\.example\.com/'
+fields = { price = { selector = "span.price", attr = "data-amount" } }
+
+[[daemon.crawls]]
+name = "nightly"
+cron = "30 2 * * *"
+args = ["--url", "https://example.com"]
+`,
+	}
+	var want *Config
+	for _, name := range []string{"config.json", "config.yaml", "config.toml"} {
+		cfg, err := Load(writeFile(t, name, files[name]))
+		if err != nil {
+			t.Fatalf("Load(%s): %v", name, err)
+		}
+		if want == nil {
+			want = cfg
+			if cfg.Workers.Pages != 4 || cfg.Workers.ImageTimeout.Std() != 30*time.Second || cfg.Workers.Images != 4 {
+				t.Errorf("Load(%s): workers %+v, want pages 4, image_timeout 30s and the default images 4", name, cfg.Workers)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("Load(%s) = %+v, want the same as config.json: %+v", name, cfg, want)
+		}
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	if _, err := Load("../../config.example.json"); err != nil {
+		t.Errorf("config.example.json: %v", err)
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "c.json", `{"workers": {"pages": 4}, "fetch": {"delay": "1s"}}`)
+	t.Setenv("GOCRAWLER_WORKERS", "7")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Workers.Pages != 7 || cfg.Fetch.Delay.Std() != time.Second {
+		t.Errorf("pages %d, delay %s: want 7 from the environment and 1s from the file", cfg.Workers.Pages, cfg.Fetch.Delay.Std())
+	}
+
+	t.Setenv(EnvFile, path)
+	if cfg, err := Load(""); err != nil || cfg.Fetch.Delay.Std() != time.Second {
+		t.Errorf("Load(\"\") with $%s: %v, %v, want the file read", EnvFile, cfg, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"c.json", `{"fetch": {"delai": "1s"}}`, "fetch.delai: unknown key"},
+		{"c.json", `{"hosts": {"example.org": {"delay": "fast"}}}`, `hosts.example.org.delay: invalid duration "fast"`},
+		{"c.json", `{"workers": {"pages": "ten"}}`, "workers.pages: expected int, got string"},
+		{"c.json", "{\n  \"workers\": {\n    \"pages\": 4,\n  }\n}", "c.json:4:"},
+		{"c.json", `{"workers": {"pages": 0}}`, "workers.pages: must be at least 1"},
+		{"c.yaml", "workers:\n  pages: [1\n", "c.yaml: yaml:"},
+		{"c.yaml", "fetch:\n  delai: 1s\n", "fetch.delai: unknown key"},
+		{"c.yml", "workers: {pages: ten}\n", "workers.pages: expected int, got string"},
+		{"c.toml", "[workers\npages = 1\n", "c.toml: toml:"},
+		{"c.toml", "[fetch]\ndelay = \"fast\"\n", `fetch.delay: invalid duration "fast"`},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeFile(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%s %q) = %v, want error containing %q", tt.name, tt.content, err, tt.want)
+		}
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load(missing file) = %v, want ErrNotExist", err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		env   map[string]string
+		check func(*Config) bool
+		err   string // KeyError source and key, "" for none
+	}{
+		{map[string]string{"GOCRAWLER_DSN": "a:b@/c"}, func(c *Config) bool { return c.Database.DSN == "a:b@/c" }, ""},
+		{map[string]string{"GOCRAWLER_IMG_WORKERS": " 8 "}, func(c *Config) bool { return c.Workers.Images == 8 }, ""},
+		{map[string]string{"GOCRAWLER_DELAY": "250ms"}, func(c *Config) bool { return c.Fetch.Delay.Std() == 250*time.Millisecond }, ""},
+		{map[string]string{"GOCRAWLER_ADAPTIVE": "true"}, func(c *Config) bool { return c.Workers.HostConcurrency.Adaptive }, ""},
+		{map[string]string{"GOCRAWLER_HOST_MAX": "6"}, func(c *Config) bool { return c.Workers.HostConcurrency.Max == 6 }, ""},
+		{map[string]string{"GOCRAWLER_USER_AGENT": "bot"}, func(c *Config) bool { return c.Fetch.UserAgent == "bot" }, ""},
+		{map[string]string{"GOCRAWLER_WORKERS": "many"}, nil, "GOCRAWLER_WORKERS: workers.pages"},
+		{map[string]string{"GOCRAWLER_PAGE_TIMEOUT": "10"}, nil, "GOCRAWLER_PAGE_TIMEOUT: fetch.page_timeout"},
+		{map[string]string{"GOCRAWLER_ADAPTIVE": "maybe"}, nil, "GOCRAWLER_ADAPTIVE: workers.host_concurrency.adaptive"},
+	}
+	for _, tt := range tests {
+		c := Default()
+		err := c.applyEnv(func(k string) (string, bool) { v, ok := tt.env[k]; return v, ok })
+		if tt.err != "" {
+			var ke *KeyError
+			if !errors.As(err, &ke) || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("applyEnv(%v) = %v, want KeyError %q", tt.env, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || !tt.check(c) {
+			t.Errorf("applyEnv(%v): err %v, override not applied", tt.env, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		key    string // KeyError.Key, "" for valid
+		modify func(*Config)
+	}{
+		{"", func(c *Config) {}},
+		{"database.dsn", func(c *Config) { c.Database.DSN = "no-slash" }},
+		{"dirs.images", func(c *Config) { c.Dirs.Images = "" }},
+		{"dirs.thumbnails", func(c *Config) { c.Dirs.Thumbnails = "" }},
+		{"workers.pages", func(c *Config) { c.Workers.Pages = 0 }},
+		{"workers.images", func(c *Config) { c.Workers.Images = 0 }},
+		{"workers.image_timeout", func(c *Config) { c.Workers.ImageTimeout = Duration(time.Millisecond) }},
+		{"workers.max_goroutines", func(c *Config) { c.Workers.MaxGoroutines = 0 }},
+		{"workers.host_concurrency.min", func(c *Config) { c.Workers.HostConcurrency.Min = 0 }},
+		{"workers.host_concurrency.min", func(c *Config) { c.Workers.Pages = 1; c.Workers.HostConcurrency.Min = 2 }},
+		{"workers.host_concurrency.max", func(c *Config) { c.Workers.HostConcurrency.Max = 1; c.Workers.HostConcurrency.Min = 2 }},
+		{"workers.host_concurrency.initial", func(c *Config) { c.Workers.HostConcurrency.Max = 3; c.Workers.HostConcurrency.Initial = 4 }},
+		{"fetch.page_timeout", func(c *Config) { c.Fetch.PageTimeout = 0 }},
+		{"fetch.image_timeout", func(c *Config) { c.Fetch.ImageTimeout = -1 }},
+		{"fetch.delay", func(c *Config) { c.Fetch.Delay = -1 }},
+		{"fetch.headers.Bad Name", func(c *Config) { c.Fetch.Headers = map[string]string{"Bad Name": "x"} }},
+		{"fetch.headers.X-Ok", func(c *Config) { c.Fetch.Headers = map[string]string{"X-Ok": "a\r\nb"} }},
+		{"hosts.https://example.com", func(c *Config) { c.Hosts = map[string]Host{"https://example.com": {}} }},
+		{"hosts.example.com:8080", func(c *Config) { c.Hosts = map[string]Host{"example.com:8080": {}} }},
+		{"hosts.", func(c *Config) { c.Hosts = map[string]Host{"": {}} }},
+		{"hosts.example.com.delay", func(c *Config) { d := Duration(-1); c.Hosts = map[string]Host{"example.com": {Delay: &d}} }},
+		{"hosts.example.com.headers.a:b", func(c *Config) { c.Hosts = map[string]Host{"example.com": {Headers: map[string]string{"a:b": "x"}}} }},
+		{"extract[0].url", func(c *Config) { c.Extract = []ExtractRule{{Fields: map[string]Field{"f": {Selector: "p"}}}} }},
+		{"extract[0].url", func(c *Config) { c.Extract = []ExtractRule{{URL: "(", Fields: map[string]Field{"f": {Selector: "p"}}}} }},
+		{"extract[0].fields", func(c *Config) { c.Extract = []ExtractRule{{URL: "."}} }},
+		{"extract[0].fields.f.selector", func(c *Config) {
+			c.Extract = []ExtractRule{{URL: ".", Fields: map[string]Field{"f": {Selector: "p:hover"}}}}
+		}},
+		{"daemon.max_concurrent", func(c *Config) { c.Daemon.MaxConcurrent = 0 }},
+		{"daemon.runs_dir", func(c *Config) { c.Daemon.RunsDir = "" }},
+	}
+	for _, tt := range tests {
+		c := Default()
+		tt.modify(c)
+		err := c.Validate()
+		if tt.key == "" {
+			if err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			continue
+		}
+		var ke *KeyError
+		if !errors.As(err, &ke) || ke.Key != tt.key {
+			t.Errorf("Validate() = %v, want KeyError for %q", err, tt.key)
+		}
+	}
+}
+
+func TestValidateDaemon(t *testing.T) {
+	crawl := func(name, cron string, args ...string) Schedule { return Schedule{Name: name, Cron: cron, Args: args} }
+	tests := []struct {
+		crawls []Schedule
+		key    string
+	}{
+		{[]Schedule{crawl("nightly", "30 2 * * *", "--url", "https://example.com"), crawl("blog.hourly", "@hourly", "--url=https://blog.example.org")}, ""},
+		{[]Schedule{crawl("", "@daily", "--url", "x")}, "daemon.crawls[0].name"},
+		{[]Schedule{crawl("a/b", "@daily", "--url", "x")}, "daemon.crawls[0].name"},
+		{[]Schedule{crawl(".hidden", "@daily", "--url", "x")}, "daemon.crawls[0].name"},
+		{[]Schedule{crawl("a", "@daily", "--url", "x"), crawl("a", "@daily", "--url", "y")}, "daemon.crawls[1].name"},
+		{[]Schedule{crawl("a", "61 * * * *", "--url", "x")}, "daemon.crawls[0].cron"},
+		{[]Schedule{crawl("a", "@daily", "--depth", "2")}, "daemon.crawls[0].args"},
+		{[]Schedule{crawl("a", "@daily", "--url", "x", "--stats", "s.json")}, "daemon.crawls[0].args"},
+		{[]Schedule{crawl("a", "@daily", "--url", "x", "-config=c.json")}, "daemon.crawls[0].args"},
+	}
+	for _, tt := range tests {
+		c := Default()
+		c.Daemon.Crawls = tt.crawls
+		err := c.Validate()
+		var ke *KeyError
+		switch {
+		case tt.key == "" && err != nil:
+			t.Errorf("Validate(%v) = %v, want nil", tt.crawls, err)
+		case tt.key != "" && (!errors.As(err, &ke) || ke.Key != tt.key):
+			t.Errorf("Validate(%v) = %v, want KeyError for %q", tt.crawls, err, tt.key)
+		}
+	}
+}
+
+func TestHostRules(t *testing.T) {
+	yes, no := true, false
+	second, zero := Duration(time.Second), Duration(0)
+	c := Default()
+	c.Fetch.Delay = Duration(100 * time.Millisecond)
+	c.Fetch.Headers = map[string]string{"Accept-Language": "en", "Cookie": "global"}
+	c.Hosts = map[string]Host{
+		"example.com":     {Delay: &second, Headers: map[string]string{"Cookie": "site"}},
+		"cdn.example.com": {JS: &no, Delay: &zero},
+		"App.Example.org": {JS: &yes},
+	}
+
+	tests := []struct {
+		host    string
+		delay   time.Duration
+		js      bool
+		cookie  string
+		hasRule bool
+	}{
+		{"example.com", time.Second, false, "site", true},
+		{"www.example.com", time.Second, false, "site", true},
+		{"cdn.example.com", 0, false, "global", true},
+		{"img.cdn.example.com", 0, false, "global", true},
+		{"notexample.com", 100 * time.Millisecond, false, "global", false},
+		{"example.com.evil.net", 100 * time.Millisecond, false, "global", false},
+		{"EXAMPLE.COM", time.Second, false, "site", true},
+		{"app.example.org", 100 * time.Millisecond, true, "global", true},
+		{"example.org", 100 * time.Millisecond, false, "global", false},
+	}
+	for _, tt := range tests {
+		if _, ok := c.host(tt.host); ok != tt.hasRule {
+			t.Errorf("host(%s) found = %v, want %v", tt.host, ok, tt.hasRule)
+		}
+		if got := c.Delay(tt.host); got != tt.delay {
+			t.Errorf("Delay(%s) = %s, want %s", tt.host, got, tt.delay)
+		}
+		if got := c.UseJS("https://"+tt.host+"/page", false); got != tt.js {
+			t.Errorf("UseJS(%s) = %v, want %v", tt.host, got, tt.js)
+		}
+		h := c.Headers(tt.host)
+		if h["Cookie"] != tt.cookie || h["Accept-Language"] != "en" {
+			t.Errorf("Headers(%s) = %v, want Cookie %q and the global Accept-Language", tt.host, h, tt.cookie)
+		}
+	}
+	if c.UseJS("https://cdn.example.com/", true) {
+		t.Error("UseJS: a host's js: false must win over --js")
+	}
+	if !c.WantsJS() {
+		t.Error("WantsJS() = false with a js: true host")
+	}
+	if c.Fetch.Headers["Cookie"] != "global" {
+		t.Error("Headers modified the global headers")
+	}
+	if Default().HasHostRules() || !c.HasHostRules() {
+		t.Error("HasHostRules: want false for the defaults and true with hosts")
+	}
+}
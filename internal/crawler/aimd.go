@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"GoCrawler/internal/fetch"
+)
+
+// HostLimiter caps the pages fetched at once from each host and adapts the
+// cap to how the host copes (additive increase, multiplicative decrease):
+// every healthy response raises a host's limit by 1/limit, so by about one
+// per round of requests, and a 5xx, 429 or timeout halves it. A response
+// slower than twice the host's usual latency is not healthy and leaves the
+// limit as it is. Limits stay between min and max. It is safe for
+// concurrent use.
+type HostLimiter struct {
+	mu      sync.Mutex
+	min     float64
+	max     float64
+	initial float64
+	hosts   map[string]*hostLimit
+}
+
+type hostLimit struct {
+	limit        float64
+	active       int
+	lowest       float64
+	highest      float64
+	backoffs     int
+	latency      float64   // smoothed fetch latency, seconds
+	baseline     float64   // the usual latency: the lowest smoothed latency, drifting up slowly
+	lastDecrease time.Time // responses to requests sent before this don't decrease again
+}
+
+// HostLimit is the concurrency state of one host.
+type HostLimit struct {
+	Limit    int `json:"limit"`    // pages fetched at once now
+	Lowest   int `json:"lowest"`   // lowest limit reached
+	Highest  int `json:"highest"`  // highest limit reached
+	Backoffs int `json:"backoffs"` // times the limit was cut
+}
+
+// Latency smoothing: the weight of a new sample, how much slower than the
+// baseline a host may get before its limit stops growing, and how fast the
+// baseline follows a host that got slower for good.
+const (
+	latencyWeight  = 0.2
+	slowFactor     = 2.0
+	baselineWeight = 0.01
+)
+
+// NewHostLimiter returns a limiter that starts every host at initial and
+// keeps its limit within [lo, hi].
+func NewHostLimiter(lo, hi, initial int) *HostLimiter {
+	lo = max(lo, 1)
+	hi = max(hi, lo)
+	return &HostLimiter{
+		min:     float64(lo),
+		max:     float64(hi),
+		initial: float64(min(max(initial, lo), hi)),
+		hosts:   make(map[string]*hostLimit),
+	}
+}
+
+func (l *HostLimiter) host(host string) *hostLimit {
+	h := l.hosts[host]
+	if h == nil {
+		h = &hostLimit{limit: l.initial, lowest: l.initial, highest: l.initial}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// TryAcquire takes a slot for a request to host if host is below its
+// limit, and reports whether it did.
+func (l *HostLimiter) TryAcquire(host string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.host(host)
+	if h.active >= int(h.limit) {
+		return false
+	}
+	h.active++
+	return true
+}
+
+// Release gives back the slot of a request to host started at started, and
+// adjusts the host's limit by the outcome: its fetch latency and error.
+func (l *HostLimiter) Release(host string, started time.Time, latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.host(host)
+	h.active--
+
+	class, code := fetch.Classify(err)
+	switch {
+	case class == fetch.ClassTimeout, code == 429, code >= 500:
+		if started.Before(h.lastDecrease) {
+			return // already backed off for this round
+		}
+		h.limit = math.Max(l.min, h.limit/2)
+		h.lowest = math.Min(h.lowest, h.limit)
+		h.backoffs++
+		h.lastDecrease = time.Now()
+	case err == nil, class == fetch.ClassStatus, class == fetch.ClassContentType:
+		if latency > 0 && h.slow(latency.Seconds()) {
+			return
+		}
+		h.limit = math.Min(l.max, h.limit+1/h.limit)
+		h.highest = math.Max(h.highest, h.limit)
+	}
+}
+
+// slow records a latency sample and reports whether the host has become
+// slow compared to its baseline.
+func (h *hostLimit) slow(sample float64) bool {
+	if h.latency == 0 {
+		h.latency, h.baseline = sample, sample
+		return false
+	}
+	h.latency += latencyWeight * (sample - h.latency)
+	if h.latency < h.baseline {
+		h.baseline = h.latency
+	} else {
+		h.baseline += baselineWeight * (h.latency - h.baseline)
+	}
+	return h.latency > slowFactor*h.baseline
+}
+
+// Limit returns the current limit of host.
+func (l *HostLimiter) Limit(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if h := l.hosts[host]; h != nil {
+		return int(h.limit)
+	}
+	return int(l.initial)
+}
+
+// Snapshot returns the state of every host seen so far.
+func (l *HostLimiter) Snapshot() map[string]HostLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]HostLimit, len(l.hosts))
+	for host, h := range l.hosts {
+		out[host] = HostLimit{
+			Limit:    int(h.limit),
+			Lowest:   int(h.lowest),
+			Highest:  int(h.highest),
+			Backoffs: h.backoffs,
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"GoCrawler/internal/fetch"
+)
+
+var (
+	errUnavailable = &fetch.StatusError{Code: 503, Status: "503 Service Unavailable"}
+	errTooMany     = &fetch.StatusError{Code: 429, Status: "429 Too Many Requests"}
+	errNotFound    = &fetch.StatusError{Code: 404, Status: "404 Not Found"}
+)
+
+// do runs one request to host through l: it takes a slot and releases it
+// with the outcome.
+func do(t *testing.T, l *HostLimiter, host string, started time.Time, latency time.Duration, err error) {
+	t.Helper()
+	if !l.TryAcquire(host) {
+		t.Fatalf("TryAcquire(%s) = false at limit %d", host, l.Limit(host))
+	}
+	l.Release(host, started, latency, err)
+}
+
+func TestHostLimiterAdditiveIncrease(t *testing.T) {
+	l := NewHostLimiter(1, 10, 2)
+	// each healthy response adds 1/limit, about one per round: 2, 2.5,
+	// 2.9, 3.24, 3.55, 3.84, 4.1
+	want := []int{2, 2, 3, 3, 3, 4}
+	for i, w := range want {
+		do(t, l, "a", time.Now(), 0, nil)
+		if got := l.Limit("a"); got != w {
+			t.Errorf("after %d responses: Limit = %d, want %d", i+1, got, w)
+		}
+	}
+	// 404s are healthy: the host answered
+	do(t, l, "a", time.Now(), 0, errNotFound)
+	if got := l.Snapshot()["a"]; got.Backoffs != 0 || got.Highest != 4 {
+		t.Errorf("after a 404: %+v, want no backoff and highest 4", got)
+	}
+	if got := l.Limit("b"); got != 2 {
+		t.Errorf("Limit(other host) = %d, want the initial 2", got)
+	}
+}
+
+func TestHostLimiterBackoffOncePerRound(t *testing.T) {
+	l := NewHostLimiter(1, 16, 8)
+	round := time.Now()
+	// a round of requests sent together fails together: one halving
+	for range 4 {
+		l.TryAcquire("a")
+	}
+	for _, err := range []error{errUnavailable, errTooMany, context.DeadlineExceeded, errUnavailable} {
+		l.Release("a", round, 0, err)
+	}
+	if got := l.Limit("a"); got != 4 {
+		t.Errorf("after one failing round: Limit = %d, want 4", got)
+	}
+	// a request sent after the backoff halves again
+	do(t, l, "a", time.Now(), 0, errUnavailable)
+	if got := l.Limit("a"); got != 2 {
+		t.Errorf("after a second round: Limit = %d, want 2", got)
+	}
+	if got := l.Snapshot()["a"]; got.Backoffs != 2 || got.Lowest != 2 || got.Highest != 8 {
+		t.Errorf("Snapshot = %+v, want 2 backoffs, lowest 2, highest 8", got)
+	}
+	// errors that say nothing about the host's load leave the limit alone
+	do(t, l, "a", time.Now(), 0, errors.New("connection refused"))
+	if got := l.Limit("a"); got != 2 {
+		t.Errorf("after a non-load error: Limit = %d, want 2", got)
+	}
+}
+
+func TestHostLimiterBounds(t *testing.T) {
+	l := NewHostLimiter(2, 4, 3)
+	for range 10 {
+		do(t, l, "a", time.Now(), 0, errUnavailable)
+	}
+	if got := l.Limit("a"); got != 2 {
+		t.Errorf("after repeated failures: Limit = %d, want min 2", got)
+	}
+	for range 50 {
+		do(t, l, "a", time.Now(), 0, nil)
+	}
+	if got := l.Limit("a"); got != 4 {
+		t.Errorf("after repeated successes: Limit = %d, want max 4", got)
+	}
+
+	tests := []struct {
+		lo, hi, initial int
+		want            int
+	}{
+		{2, 4, 1, 2},
+		{2, 4, 9, 4},
+		{0, 0, 0, 1},
+		{5, 3, 4, 5},
+	}
+	for _, tt := range tests {
+		if got := NewHostLimiter(tt.lo, tt.hi, tt.initial).Limit("a"); got != tt.want {
+			t.Errorf("NewHostLimiter(%d, %d, %d): initial limit %d, want %d", tt.lo, tt.hi, tt.initial, got, tt.want)
+		}
+	}
+}
+
+func TestHostLimiterSlowHost(t *testing.T) {
+	l := NewHostLimiter(1, 100, 10)
+	for range 10 {
+		do(t, l, "a", time.Now(), 100*time.Millisecond, nil)
+	}
+	before := l.Limit("a")
+	for range 10 {
+		do(t, l, "a", time.Now(), time.Second, nil)
+	}
+	if got := l.Limit("a"); got != before {
+		t.Errorf("Limit after slow responses = %d, want unchanged %d", got, before)
+	}
+}
+
+func TestHostLimiterTryAcquire(t *testing.T) {
+	l := NewHostLimiter(1, 4, 2)
+	if !l.TryAcquire("a") || !l.TryAcquire("a") {
+		t.Fatal("TryAcquire below the limit = false")
+	}
+	if l.TryAcquire("a") {
+		t.Error("TryAcquire at the limit = true")
+	}
+	if !l.TryAcquire("b") {
+		t.Error("TryAcquire for another host = false")
+	}
+	l.Release("a", time.Now(), 0, errors.New("connection refused"))
+	if !l.TryAcquire("a") {
+		t.Error("TryAcquire after Release = false")
+	}
+}
+
+// TestWorkerPoolHoldsBackBusyHost checks that a job for a host at its limit
+// waits without taking a worker, so jobs for other hosts get through.
+func TestWorkerPoolHoldsBackBusyHost(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pool := NewWorkerPool(ctx, 2, 10, 10)
+	pool.Limiter = NewHostLimiter(1, 1, 1)
+
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	pool.Start(func(ctx context.Context, job CrawlJob) CrawlResult {
+		if HostOf(job.URL) == "a.example" {
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+			<-gate
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}
+		return CrawlResult{URL: job.URL}
+	})
+
+	for _, u := range []string{"https://a.example/1", "https://a.example/2", "https://b.example/1"} {
+		pool.Jobs() <- CrawlJob{URL: u}
+	}
+	select {
+	case res := <-pool.Results():
+		if res.URL != "https://b.example/1" {
+			t.Errorf("first result %s, want the job for the other host", res.URL)
+		}
+	case <-ctx.Done():
+		t.Fatal("the job for the other host waited behind the busy host")
+	}
+
+	close(gate)
+	var rest []string
+	for range 2 {
+		select {
+		case res := <-pool.Results():
+			rest = append(rest, res.URL)
+		case <-ctx.Done():
+			t.Fatalf("held-back job never ran, got %v", rest)
+		}
+	}
+	if rest[0] != "https://a.example/1" || rest[1] != "https://a.example/2" {
+		t.Errorf("results for the busy host %v, want them in order", rest)
+	}
+	if maxActive != 1 {
+		t.Errorf("jobs for the busy host at once: %d, want 1", maxActive)
+	}
+	pool.Stop()
+}
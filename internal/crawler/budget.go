@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Budget caps how much a single crawl may do. Zero values mean unlimited.
+//
+// Pages and images are accounted by the dispatch loop; bytes may be added
+// from any goroutine.
+type Budget struct {
+	MaxPages        int
+	MaxImages       int
+	MaxBytes        int64
+	MaxDuration     time.Duration
+	MaxPagesPerHost int
+
+	mu        sync.Mutex
+	start     time.Time
+	pages     int
+	images    int
+	bytes     int64
+	hostPages map[string]int
+}
+
+func (b *Budget) Start() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.start = time.Now()
+	b.hostPages = make(map[string]int)
+}
+
+// Exhausted reports which crawl-wide budget (bytes or duration) ran out,
+// or "" if the crawl may continue.
+func (b *Budget) Exhausted() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.MaxBytes > 0 && b.bytes >= b.MaxBytes {
+		return "max-bytes"
+	}
+	if b.MaxDuration > 0 && !b.start.IsZero() && time.Since(b.start) >= b.MaxDuration {
+		return "max-duration"
+	}
+	return ""
+}
+
+func (b *Budget) PageAvailable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.MaxPages <= 0 || b.pages < b.MaxPages
+}
+
+func (b *Budget) UsePage() {
+	b.mu.Lock()
+	b.pages++
+	b.mu.Unlock()
+}
+
+// ReturnPages gives back n pages taken with UsePage that were never
+// fetched, e.g. the jobs of an expired lease that are queued again.
+func (b *Budget) ReturnPages(n int) {
+	b.mu.Lock()
+	b.pages = max(b.pages-n, 0)
+	b.mu.Unlock()
+}
+
+func (b *Budget) ImageAvailable() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.MaxImages <= 0 || b.images < b.MaxImages
+}
+
+func (b *Budget) UseImage() {
+	b.mu.Lock()
+	b.images++
+	b.mu.Unlock()
+}
+
+func (b *Budget) AddBytes(n int64) {
+	b.mu.Lock()
+	b.bytes += n
+	b.mu.Unlock()
+}
+
+// HostAvailable reports whether the per-host cap leaves a page slot for
+// the host of rawURL, without taking it.
+func (b *Budget) HostAvailable(rawURL string) bool {
+	if b.MaxPagesPerHost <= 0 {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hostPages[u.Hostname()] < b.MaxPagesPerHost
+}
+
+// TakeHost reserves a page slot for the host of rawURL and reports whether
+// the per-host cap allowed it.
+func (b *Budget) TakeHost(rawURL string) bool {
+	if b.MaxPagesPerHost <= 0 {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.hostPages == nil {
+		b.hostPages = make(map[string]int)
+	}
+	if b.hostPages[host] >= b.MaxPagesPerHost {
+		return false
+	}
+	b.hostPages[host]++
+	return true
+}
+
+func (b *Budget) Usage() (pages, images int, bytes int64, elapsed time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pages, b.images, b.bytes, time.Since(b.start)
+}
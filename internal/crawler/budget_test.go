@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBudgetPages(t *testing.T) {
+	b := &Budget{MaxPages: 2}
+	b.Start()
+	for i := range 2 {
+		if !b.PageAvailable() {
+			t.Fatalf("PageAvailable after %d pages = false, want true", i)
+		}
+		b.UsePage()
+	}
+	if b.PageAvailable() {
+		t.Error("PageAvailable at max-pages = true")
+	}
+	b.ReturnPages(1)
+	if !b.PageAvailable() {
+		t.Error("PageAvailable after ReturnPages = false")
+	}
+	b.ReturnPages(5)
+	if pages, _, _, _ := b.Usage(); pages != 0 {
+		t.Errorf("pages after returning more than used = %d, want 0", pages)
+	}
+}
+
+func TestBudgetImages(t *testing.T) {
+	b := &Budget{MaxImages: 1}
+	b.Start()
+	if !b.ImageAvailable() {
+		t.Fatal("ImageAvailable before any image = false")
+	}
+	b.UseImage()
+	if b.ImageAvailable() {
+		t.Error("ImageAvailable at max-images = true")
+	}
+	if _, images, _, _ := b.Usage(); images != 1 {
+		t.Errorf("images = %d, want 1", images)
+	}
+}
+
+func TestBudgetExhausted(t *testing.T) {
+	b := &Budget{MaxBytes: 100}
+	b.Start()
+	b.AddBytes(99)
+	if got := b.Exhausted(); got != "" {
+		t.Errorf("Exhausted below max-bytes = %q, want \"\"", got)
+	}
+	b.AddBytes(1)
+	if got := b.Exhausted(); got != "max-bytes" {
+		t.Errorf("Exhausted at max-bytes = %q, want max-bytes", got)
+	}
+
+	b = &Budget{MaxDuration: 20 * time.Millisecond}
+	if got := b.Exhausted(); got != "" {
+		t.Errorf("Exhausted before Start = %q, want \"\"", got)
+	}
+	b.Start()
+	if got := b.Exhausted(); got != "" {
+		t.Errorf("Exhausted right after Start = %q, want \"\"", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := b.Exhausted(); got != "max-duration" {
+		t.Errorf("Exhausted after max-duration = %q, want max-duration", got)
+	}
+
+	unlimited := &Budget{}
+	unlimited.Start()
+	unlimited.AddBytes(1 << 40)
+	for range 1000 {
+		unlimited.UsePage()
+		unlimited.UseImage()
+	}
+	if got := unlimited.Exhausted(); got != "" || !unlimited.PageAvailable() || !unlimited.ImageAvailable() {
+		t.Errorf("zero budget: Exhausted %q, pages %v, images %v, want unlimited", got, unlimited.PageAvailable(), unlimited.ImageAvailable())
+	}
+}
+
+func TestBudgetPerHost(t *testing.T) {
+	b := &Budget{MaxPagesPerHost: 2}
+	b.Start()
+	for _, u := range []string{"https://a.example/1", "http://a.example:8080/2"} {
+		if !b.HostAvailable(u) || !b.TakeHost(u) {
+			t.Fatalf("TakeHost(%s) below the cap = false", u)
+		}
+	}
+	if b.HostAvailable("https://a.example/3") || b.TakeHost("https://a.example/3") {
+		t.Error("host at the cap still available")
+	}
+	for range 3 {
+		if !b.HostAvailable("https://b.example/1") {
+			t.Fatal("HostAvailable(other host) = false")
+		}
+	}
+	if !b.TakeHost("https://b.example/1") || !b.TakeHost("https://b.example/2") {
+		t.Error("HostAvailable took a slot of the host")
+	}
+}
@@ -2,51 +2,142 @@ package crawler
 
 import (
 	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"GoCrawler/internal/fetch"
 )
 
+// ProcessJob processes job without page processors.
 func ProcessJob(ctx context.Context, job CrawlJob) CrawlResult {
-	body, err := FetchPage(ctx, job.URL, job.UseJS)
+	return (&Crawler{}).Process(ctx, job)
+}
+
+// Process fetches the page of job and returns its text, language, links,
+// images and the records the page processors emitted. A page whose language
+// is known but not in job.Languages, and a page whose preferred hreflang
+// variant (job.Hreflang) is another page, are returned without links or
+// images, marked as skipped; the latter links to the preferred variant.
+func (c *Crawler) Process(ctx context.Context, job CrawlJob) CrawlResult {
+	var waited atomic.Int64
+	start := time.Now()
+	body, header, err := FetchPage(fetch.WithWaitTimer(ctx, &waited), job.URL, job.UseJS)
+	elapsed := time.Since(start) - time.Duration(waited.Load())
 	if err != nil {
-		return CrawlResult{URL: job.URL, Depth: job.Depth, Err: err}
+		return CrawlResult{URL: job.URL, Depth: job.Depth, Distance: job.Distance, Fetch: elapsed, Err: err}
 	}
 
-	linksRaw, err := ExtractLinks(body)
+	size := int64(len(body))
+
+	doc, err := ExtractDocument(body, job.LinkSources)
 	if err != nil {
-		return CrawlResult{URL: job.URL, Depth: job.Depth, Err: err}
+		return CrawlResult{URL: job.URL, Depth: job.Depth, Distance: job.Distance, Bytes: size, Fetch: elapsed, Err: err}
 	}
-	imagesRaw, err := ExtractImages(body)
-	if err != nil {
-		return CrawlResult{URL: job.URL, Depth: job.Depth, Err: err}
+
+	base := job.URL
+	if doc.BaseURL != "" {
+		if b, err := NormalizeURL(job.URL, doc.BaseURL); err == nil && b != "" {
+			base = b
+		}
+	}
+
+	var alternates []Alternate
+	for _, a := range doc.Alternates {
+		if n, err := NormalizeURL(base, a.URL); err == nil && n != "" {
+			a.URL = n
+			alternates = append(alternates, a)
+		}
+	}
+	variant := VariantGroup(job.URL, alternates)
+
+	lang := pageLanguage(doc, header)
+	skipped := CrawlResult{URL: job.URL, Title: doc.Title, Lang: lang, Alternates: alternates, Variant: variant, Depth: job.Depth, Distance: job.Distance, Bytes: size, Fetch: elapsed}
+	if job.Hreflang != "" && len(alternates) > 0 {
+		if u := PreferredVariant(alternates, job.Hreflang); u != "" && u != job.URL {
+			skipped.Skipped = "variant"
+			skipped.Links = []Link{{URL: u, Source: "hreflang", Rel: []string{"alternate"}}}
+			return skipped
+		}
+	}
+	if len(job.Languages) > 0 && lang != "" && !slices.Contains(job.Languages, lang) {
+		skipped.Skipped = "lang"
+		return skipped
 	}
 
-	links := make([]string, 0, len(linksRaw))
-	for _, l := range linksRaw {
-		n, err := NormalizeURL(job.URL, l)
+	found, foundImages := doc.Links, doc.Images
+	var records []Record
+	if len(c.processors) > 0 {
+		page := &Page{URL: job.URL, BaseURL: base, Header: header, Body: body, Doc: doc, Depth: job.Depth, Distance: job.Distance}
+		emitted := c.runProcessors(ctx, page)
+		found = append(found[:len(found):len(found)], emitted.links...)
+		foundImages = append(foundImages[:len(foundImages):len(foundImages)], emitted.images...)
+		records = emitted.records
+	}
+
+	links := make([]Link, 0, len(found))
+	for _, l := range found {
+		n, err := NormalizeURL(base, l.URL)
 		if err == nil && n != "" {
-			links = append(links, n)
+			l.URL = n
+			links = append(links, l)
 		}
 	}
 
-	images := make([]string, 0, len(imagesRaw))
-	for _, img := range imagesRaw {
-		n, err := NormalizeURL(job.URL, img)
+	images := make([]ImageRef, 0, len(foundImages))
+	for _, img := range foundImages {
+		n, err := NormalizeURL(base, img.URL)
 		if err == nil && n != "" {
-			images = append(images, n)
+			img.URL = n
+			images = append(images, img)
 		}
 	}
 
 	if !job.FollowExternal {
-		domain, err := ExtractDomain(job.URL) // eTLD+1
-		if err == nil && domain != "" {
-			links = FilterSameDomain(links, domain)
+		if IsLocalURL(job.URL) {
+			links = FilterLocalLinks(links)
+		} else if domain, err := ExtractDomain(job.URL); err == nil && domain != "" { // eTLD+1
+			links = FilterSameDomainLinks(links, domain)
 		}
 	}
 
+	description := doc.Meta["description"]
+	if description == "" {
+		description = doc.Meta["og:description"]
+	}
+
 	return CrawlResult{
-		URL:       job.URL,
-		Links:     Unique(links),
-		ImageURLs: Unique(images),
-		Depth:     job.Depth,
-		Err:       nil,
+		URL:         job.URL,
+		Title:       doc.Title,
+		Description: strings.Join(strings.Fields(description), " "),
+		Lang:        lang,
+		Text:        doc.Text,
+		Alternates:  alternates,
+		Variant:     variant,
+		Links:       UniqueLinks(links),
+		Images:      UniqueImages(images),
+		Records:     records,
+		Depth:       job.Depth,
+		Distance:    job.Distance,
+		Bytes:       size,
+		Fetch:       elapsed,
+		Err:         nil,
+	}
+}
+
+// pageLanguage returns the language of a page: the one it declares with
+// <html lang>, a Content-Language meta tag or header, or else the one
+// detected from its text.
+func pageLanguage(doc *Document, header http.Header) string {
+	for _, declared := range []string{doc.Lang, doc.Meta["content-language"], header.Get("Content-Language")} {
+		// "en, fr" declares several audiences, not the page's language
+		if !strings.Contains(declared, ",") {
+			if lang := primaryLanguage(declared); lang != "" {
+				return lang
+			}
+		}
 	}
+	return DetectLanguage(doc.Text)
 }
@@ -0,0 +1,322 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document is everything ProcessJob needs from a page, gathered in one
+// streaming pass over the HTML.
+type Document struct {
+	Title      string
+	BaseURL    string
+	Lang       string            // as declared by <html lang>
+	Meta       map[string]string // keyed by lowercased name, property or http-equiv
+	Links      []Link
+	Images     []ImageRef
+	Text       string      // main readable text, boilerplate stripped, one block per line
+	Alternates []Alternate // language variants from <link rel="alternate" hreflang>, unresolved
+}
+
+type figureFrame struct {
+	start   int // index of the first image inside the figure
+	caption strings.Builder
+}
+
+// ExtractDocument tokenizes htmlBody once and returns its title, language,
+// meta tags, base URL, links, images and main text. It never builds a DOM.
+func ExtractDocument(htmlBody []byte, opts LinkOptions) (*Document, error) {
+	doc := &Document{Meta: make(map[string]string)}
+	z := html.NewTokenizer(bytes.NewReader(htmlBody))
+
+	var (
+		inTitle    bool
+		title      strings.Builder
+		heading    string
+		inHeading  bool
+		headingBuf strings.Builder
+		headingImg int
+		figures    []*figureFrame
+		inCaption  bool
+		pictureImg = -1
+		rawText    bool // text token belongs to <script>/<style>
+		anchor     = -1 // index in doc.Links of the open <a>
+		anchorBuf  strings.Builder
+		text       textExtractor
+	)
+
+	addImage := func(ref ImageRef) {
+		ref.Heading = heading
+		doc.Images = append(doc.Images, ref)
+	}
+	addSrcset := func(base ImageRef, v, sizes string) {
+		for _, c := range parseSrcset(v) {
+			if c.URL == "" {
+				continue
+			}
+			ref := base
+			ref.URL = c.URL
+			ref.Descriptor = c.Descriptor
+			ref.Sizes = sizes
+			addImage(ref)
+		}
+	}
+	addLink := func(source string, attrs map[string]string, raw string) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return
+		}
+		doc.Links = append(doc.Links, Link{URL: raw, Source: source, Rel: strings.Fields(strings.ToLower(attrs["rel"]))})
+	}
+	closeAnchor := func() {
+		if anchor >= 0 {
+			doc.Links[anchor].Text = strings.Join(strings.Fields(anchorBuf.String()), " ")
+			anchor = -1
+		}
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			closeAnchor()
+			finishDocument(doc, title.String())
+			doc.Text = text.String()
+			return doc, nil
+
+		case html.TextToken:
+			if rawText {
+				rawText = false
+				continue
+			}
+			raw := z.Text()
+			if inTitle {
+				title.Write(raw)
+				continue
+			}
+			text.text(raw, anchor >= 0)
+			if anchor >= 0 {
+				anchorBuf.Write(raw)
+				anchorBuf.WriteByte(' ')
+			}
+			if inHeading {
+				headingBuf.Write(raw)
+				headingBuf.WriteByte(' ')
+			}
+			if inCaption && len(figures) > 0 {
+				f := figures[len(figures)-1]
+				f.caption.Write(raw)
+				f.caption.WriteByte(' ')
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			rawText = false
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			if tag == "a" {
+				closeAnchor() // <a> never nests
+			}
+			var attrs map[string]string
+			if hasAttr {
+				attrs = tagAttrs(z)
+			}
+			text.open(tag, attrs, tt == html.SelfClosingTagToken)
+
+			switch tag {
+			case "script", "style":
+				rawText = tt == html.StartTagToken
+				continue
+			case "title":
+				inTitle = tt == html.StartTagToken && doc.Title == "" && title.Len() == 0
+				continue
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				inHeading = tt == html.StartTagToken
+				headingBuf.Reset()
+				headingImg = len(doc.Images)
+				continue
+			case "figure":
+				figures = append(figures, &figureFrame{start: len(doc.Images)})
+				continue
+			case "figcaption":
+				inCaption = tt == html.StartTagToken
+				continue
+			case "picture":
+				pictureImg = len(doc.Images)
+				continue
+			}
+
+			if attrs == nil {
+				continue
+			}
+
+			switch tag {
+			case "html":
+				if doc.Lang == "" {
+					doc.Lang = strings.TrimSpace(attrs["lang"])
+				}
+				if doc.Lang == "" {
+					doc.Lang = strings.TrimSpace(attrs["xml:lang"])
+				}
+
+			case "base":
+				if doc.BaseURL == "" {
+					doc.BaseURL = strings.TrimSpace(attrs["href"])
+				}
+
+			case "meta":
+				key := attrs["name"]
+				if key == "" {
+					key = attrs["property"]
+				}
+				if key == "" {
+					key = attrs["http-equiv"]
+				}
+				if key != "" {
+					doc.Meta[strings.ToLower(key)] = strings.TrimSpace(attrs["content"])
+				}
+				if opts.MetaRefresh && strings.EqualFold(attrs["http-equiv"], "refresh") {
+					addLink(tag, attrs, refreshURL(attrs["content"]))
+				}
+
+			case "a":
+				n := len(doc.Links)
+				addLink(tag, attrs, attrs["href"])
+				if len(doc.Links) > n && tt == html.StartTagToken {
+					anchor = n
+					anchorBuf.Reset()
+				}
+			case "area":
+				if opts.Area {
+					n := len(doc.Links)
+					addLink(tag, attrs, attrs["href"])
+					if len(doc.Links) > n {
+						doc.Links[n].Text = strings.TrimSpace(attrs["alt"])
+					}
+				}
+			case "iframe", "frame":
+				if opts.Frames {
+					addLink(tag, attrs, attrs["src"])
+				}
+			case "link":
+				if opts.LinkRel && followableRel(attrs["rel"], attrs["hreflang"], attrs["type"]) {
+					addLink(tag, attrs, attrs["href"])
+				}
+				if lang, href := strings.TrimSpace(attrs["hreflang"]), strings.TrimSpace(attrs["href"]); lang != "" && href != "" &&
+					slices.Contains(strings.Fields(strings.ToLower(attrs["rel"])), "alternate") {
+					doc.Alternates = append(doc.Alternates, Alternate{Lang: strings.ToLower(lang), URL: href})
+				}
+			case "form":
+				if opts.Forms && isGetForm(attrs["method"]) {
+					addLink(tag, attrs, attrs["action"])
+				}
+
+			case "img":
+				base := ImageRef{Alt: strings.TrimSpace(attrs["alt"]), Title: strings.TrimSpace(attrs["title"])}
+				if anchor >= 0 {
+					anchorBuf.WriteString(base.Alt)
+					anchorBuf.WriteByte(' ')
+				}
+				for _, key := range []string{"src", "data-src", "data-original", "data-lazy-src", "data-url"} {
+					if v := strings.TrimSpace(attrs[key]); v != "" {
+						ref := base
+						ref.URL = v
+						addImage(ref)
+					}
+				}
+				for _, key := range []string{"srcset", "data-srcset"} {
+					if v := strings.TrimSpace(attrs[key]); v != "" {
+						addSrcset(base, v, strings.TrimSpace(attrs["sizes"]))
+					}
+				}
+				if pictureImg >= 0 {
+					for i := pictureImg; i < len(doc.Images); i++ {
+						if doc.Images[i].Alt == "" && doc.Images[i].Title == "" {
+							doc.Images[i].Alt = base.Alt
+							doc.Images[i].Title = base.Title
+						}
+					}
+				}
+
+			case "source":
+				if v := strings.TrimSpace(attrs["srcset"]); v != "" {
+					addSrcset(ImageRef{}, v, strings.TrimSpace(attrs["sizes"]))
+				}
+
+			case "image":
+				v := strings.TrimSpace(attrs["href"])
+				if v == "" {
+					v = strings.TrimSpace(attrs["xlink:href"])
+				}
+				if v != "" {
+					addImage(ImageRef{URL: v})
+				}
+			}
+
+		case html.EndTagToken:
+			rawText = false
+			name, _ := z.TagName()
+			text.close(string(name))
+			switch string(name) {
+			case "a":
+				closeAnchor()
+			case "title":
+				inTitle = false
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				if inHeading {
+					inHeading = false
+					heading = strings.Join(strings.Fields(headingBuf.String()), " ")
+					// images inside the heading belong to it, not the previous one
+					for i := headingImg; i < len(doc.Images); i++ {
+						doc.Images[i].Heading = heading
+					}
+				}
+			case "figcaption":
+				inCaption = false
+			case "figure":
+				if len(figures) == 0 {
+					continue
+				}
+				f := figures[len(figures)-1]
+				figures = figures[:len(figures)-1]
+				caption := strings.Join(strings.Fields(f.caption.String()), " ")
+				for i := f.start; i < len(doc.Images); i++ {
+					if doc.Images[i].Figcaption == "" {
+						doc.Images[i].Figcaption = caption
+					}
+				}
+			case "picture":
+				pictureImg = -1
+			}
+		}
+	}
+}
+
+// tagAttrs reads the current tag's attributes, keeping the first value of
+// each key like the HTML parser does.
+func tagAttrs(z *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string, 4)
+	for {
+		key, val, more := z.TagAttr()
+		k := string(key)
+		if _, ok := attrs[k]; !ok {
+			attrs[k] = string(val)
+		}
+		if !more {
+			return attrs
+		}
+	}
+}
+
+func finishDocument(doc *Document, title string) {
+	doc.Title = strings.Join(strings.Fields(title), " ")
+	for i := range doc.Images {
+		doc.Images[i].PageTitle = doc.Title
+	}
+}
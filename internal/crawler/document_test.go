@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestExtractDocumentMatchesDOM checks the streaming parser against the DOM
+// reference on the benchmark pages.
+func TestExtractDocumentMatchesDOM(t *testing.T) {
+	opts := DefaultLinkOptions()
+	opts.Forms = true
+	for _, sections := range []int{1, 10, 200} {
+		page := benchPage(sections)
+		doc, err := ExtractDocument(page, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		links, err := extractLinksDOM(page, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		images, err := extractImagesDOM(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(doc.Links) != len(links) {
+			t.Errorf("%d sections: %d links, DOM has %d", sections, len(doc.Links), len(links))
+		}
+		for i := range min(len(doc.Links), len(links)) {
+			if !reflect.DeepEqual(doc.Links[i], links[i]) {
+				t.Errorf("%d sections: link %d is %+v, DOM has %+v", sections, i, doc.Links[i], links[i])
+				break
+			}
+		}
+		if len(doc.Images) != len(images) {
+			t.Errorf("%d sections: %d images, DOM has %d", sections, len(doc.Images), len(images))
+		}
+		for i := range min(len(doc.Images), len(images)) {
+			if doc.Images[i] != images[i] {
+				t.Errorf("%d sections: image %d is %+v, DOM has %+v", sections, i, doc.Images[i], images[i])
+				break
+			}
+		}
+	}
+}
+
+func TestFollowableRel(t *testing.T) {
+	tests := []struct {
+		rel, hreflang, typ string
+		want               bool
+	}{
+		{"next", "", "", true},
+		{"prev", "", "", true},
+		{"Previous", "", "", true},
+		{"alternate", "de", "", true},
+		{"alternate", "", "text/html", true},
+		{"alternate", "", "text/html; charset=utf-8", true},
+		{"alternate", "", "", false},
+		{"alternate", "", "application/rss+xml", false},
+		{"alternate", "", "application/atom+xml", false},
+		{"alternate stylesheet", "", "", false},
+		{"alternate stylesheet", "", "text/html", false},
+		{"stylesheet", "", "text/css", false},
+		{"canonical", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := followableRel(tt.rel, tt.hreflang, tt.typ); got != tt.want {
+			t.Errorf("followableRel(%q, %q, %q) = %v, want %v", tt.rel, tt.hreflang, tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package crawler
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractLinksDOM and extractImagesDOM are the DOM-based extractors that
+// ExtractDocument replaced. They are kept only as the reference its output
+// is compared with (TestExtractDocumentMatchesDOM) and benchmarked against.
+
+func extractLinksDOM(htmlBody []byte, opts LinkOptions) ([]Link, error) {
+	doc, err := html.Parse(bytes.NewReader(htmlBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var links []Link
+
+	add := func(n *html.Node, raw string) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return
+		}
+		links = append(links, Link{URL: raw, Source: n.Data, Rel: strings.Fields(strings.ToLower(attr(n, "rel")))})
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "a":
+				add(n, attr(n, "href"))
+				if len(links) > 0 && links[len(links)-1].Source == "a" && attr(n, "href") != "" {
+					links[len(links)-1].Text = anchorText(n)
+				}
+			case "area":
+				if opts.Area {
+					add(n, attr(n, "href"))
+					if len(links) > 0 && links[len(links)-1].Source == "area" && attr(n, "href") != "" {
+						links[len(links)-1].Text = attr(n, "alt")
+					}
+				}
+			case "iframe", "frame":
+				if opts.Frames {
+					add(n, attr(n, "src"))
+				}
+			case "link":
+				if opts.LinkRel && followableRel(attr(n, "rel"), attr(n, "hreflang"), attr(n, "type")) {
+					add(n, attr(n, "href"))
+				}
+			case "meta":
+				if opts.MetaRefresh && strings.EqualFold(attr(n, "http-equiv"), "refresh") {
+					add(n, refreshURL(attr(n, "content")))
+				}
+			case "form":
+				if opts.Forms && isGetForm(attr(n, "method")) {
+					add(n, attr(n, "action"))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+
+	walk(doc)
+	return links, nil
+}
+
+func extractImagesDOM(htmlBody []byte) ([]ImageRef, error) {
+	doc, err := html.Parse(bytes.NewReader(htmlBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var images []ImageRef
+
+	pageTitle := ""
+	if t := findElement(doc, "title"); t != nil {
+		pageTitle = textContent(t)
+	}
+	heading := ""
+
+	addSrcset := func(base ImageRef, v, sizes string) {
+		for _, c := range parseSrcset(v) {
+			if c.URL == "" {
+				continue
+			}
+			ref := base
+			ref.URL = c.URL
+			ref.Descriptor = c.Descriptor
+			ref.Sizes = sizes
+			images = append(images, ref)
+		}
+	}
+
+	var walk func(n *html.Node, caption string)
+	walk = func(n *html.Node, caption string) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				heading = textContent(n)
+
+			case "figure":
+				if fc := findElement(n, "figcaption"); fc != nil {
+					caption = textContent(fc)
+				}
+
+			case "img":
+				base := ImageRef{
+					Alt:        attr(n, "alt"),
+					Title:      attr(n, "title"),
+					Figcaption: caption,
+					Heading:    heading,
+					PageTitle:  pageTitle,
+				}
+				sizes := attr(n, "sizes")
+				for _, a := range n.Attr {
+					key := strings.ToLower(a.Key)
+					val := strings.TrimSpace(a.Val)
+					if val == "" {
+						continue
+					}
+					switch key {
+					case "src", "data-src", "data-original", "data-lazy-src", "data-url":
+						ref := base
+						ref.URL = val
+						images = append(images, ref)
+					case "srcset", "data-srcset":
+						addSrcset(base, val, sizes)
+					}
+				}
+
+			case "source":
+				base := ImageRef{Figcaption: caption, Heading: heading, PageTitle: pageTitle}
+				if img := pictureImg(n); img != nil {
+					base.Alt = attr(img, "alt")
+					base.Title = attr(img, "title")
+				}
+				for _, a := range n.Attr {
+					if strings.ToLower(a.Key) == "srcset" {
+						addSrcset(base, strings.TrimSpace(a.Val), attr(n, "sizes"))
+					}
+				}
+
+			case "image":
+				for _, a := range n.Attr {
+					key := strings.ToLower(a.Key)
+					val := strings.TrimSpace(a.Val)
+					if val == "" {
+						continue
+					}
+					if key == "href" || key == "xlink:href" {
+						images = append(images, ImageRef{
+							URL:        val,
+							Figcaption: caption,
+							Heading:    heading,
+							PageTitle:  pageTitle,
+						})
+					}
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, caption)
+		}
+	}
+
+	walk(doc, "")
+	return images, nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.ToLower(a.Key) == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// pictureImg returns the <img> fallback of the <picture> a <source> belongs to.
+func pictureImg(source *html.Node) *html.Node {
+	p := source.Parent
+	if p == nil || p.Type != html.ElementNode || p.Data != "picture" {
+		return nil
+	}
+	for c := p.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "img" {
+			return c
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// anchorText is the visible text of a link, with linked images contributing
+// their alt text.
+func anchorText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		case n.Type == html.ElementNode && n.Data == "img":
+			sb.WriteString(attr(n, "alt"))
+			sb.WriteByte(' ')
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style"):
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
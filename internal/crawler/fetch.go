@@ -2,38 +2,37 @@ package crawler
 
 import (
 	"context"
-	"errors"
 	"io"
 	"net/http"
-	"time"
+
+	"GoCrawler/internal/fetch"
 )
 
-func FetchHTML(ctx context.Context, url string) ([]byte, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+// FetchHTML fetches url and returns the body and the response headers.
+func FetchHTML(ctx context.Context, url string) ([]byte, http.Header, error) {
+	client := fetch.NewClient(fetch.PageTimeout)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	req.Header.Set("User-Agent", "GoCrawler/1.0 (+github.com/you)")
+	req.Header.Set("User-Agent", fetch.UserAgent)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("non-200 status: " + resp.Status)
+	if err := fetch.CheckStatus(resp); err != nil {
+		return nil, nil, err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return body, nil
+	return body, resp.Header, nil
 }
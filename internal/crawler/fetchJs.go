@@ -2,23 +2,50 @@ package crawler
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 	"time"
 
+	"GoCrawler/internal/fetch"
+
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 )
 
-func FetchHTMLJS(ctx context.Context, url string) ([]byte, error) {
+// FetchHTMLJS renders rawURL in the headless browser. The per-host delay
+// and headers of fetch.Hosts apply to it as to plain fetches; the headers
+// are sent with every request the page makes.
+func FetchHTMLJS(ctx context.Context, rawURL string) ([]byte, error) {
+	var headers map[string]string
+	if fetch.Hosts != nil {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if headers, err = fetch.Hosts.Prepare(ctx, u.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+
 	cctx, cancel := chromedp.NewContext(ctx)
 	defer cancel()
 
 	var renderedHTML string
 
-	tasks := chromedp.Tasks{
-		chromedp.Navigate(url),
+	var tasks chromedp.Tasks
+	if len(headers) > 0 {
+		extra := make(network.Headers, len(headers))
+		for k, v := range headers {
+			extra[k] = v
+		}
+		tasks = append(tasks, network.Enable(), network.SetExtraHTTPHeaders(extra))
+	}
+	tasks = append(tasks,
+		chromedp.Navigate(rawURL),
 		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(1 * time.Second),
+		chromedp.Sleep(1*time.Second),
 		chromedp.OuterHTML("html", &renderedHTML, chromedp.ByQuery),
-	}
+	)
 
 	err := chromedp.Run(cctx, tasks)
 	if err != nil {
@@ -28,11 +55,13 @@ func FetchHTMLJS(ctx context.Context, url string) ([]byte, error) {
 	return []byte(renderedHTML), nil
 }
 
-func FetchPage(ctx context.Context, url string, useJS bool) ([]byte, error) {
+// FetchPage fetches url, rendered by the headless browser when useJS is
+// set. Rendered pages have no response headers.
+func FetchPage(ctx context.Context, url string, useJS bool) ([]byte, http.Header, error) {
 	if useJS {
 		body, err := FetchHTMLJS(ctx, url)
 		if err == nil {
-			return body, nil
+			return body, nil, nil
 		}
 	}
 
@@ -0,0 +1,181 @@
+package crawler
+
+import "log/slog"
+
+// Frontier owns the visited set and the queue of pages still to crawl.
+// It is not safe for concurrent use; callers serialize access.
+type Frontier struct {
+	seed    string
+	tmpl    CrawlJob
+	traps   *TrapDetector
+	budget  *Budget
+	visited map[string]struct{}
+	queue   []CrawlJob
+	skipped map[string]int
+
+	// deferred holds the URLs the budget rejected, with the budget's
+	// name. They are not visited, so a later Add may still queue them,
+	// e.g. after pages were returned to the budget.
+	deferred map[string]string
+}
+
+// NewFrontier returns a frontier resolving links against seed. Every queued
+// job copies FollowExternal, UseJS, LinkSources, Languages and Hreflang
+// from tmpl.
+func NewFrontier(seed string, tmpl CrawlJob, traps *TrapDetector, budget *Budget) *Frontier {
+	return &Frontier{
+		seed:     seed,
+		tmpl:     tmpl,
+		traps:    traps,
+		budget:   budget,
+		visited:  make(map[string]struct{}, 4096),
+		queue:    make([]CrawlJob, 0, 4096),
+		skipped:  make(map[string]int),
+		deferred: make(map[string]string),
+	}
+}
+
+// Add queues raw at the given remaining depth and distance from the seed
+// unless it was seen before, looks like a crawl trap or is over budget. A
+// URL over budget is not marked as seen, so adding it again queues it if
+// the budget allows it by then.
+func (f *Frontier) Add(raw string, depth, distance int) bool {
+	if depth < 0 {
+		return false
+	}
+	norm, err := NormalizeURL(f.seed, raw) // base is seed
+	if err != nil || norm == "" {
+		return false
+	}
+	if _, ok := f.visited[norm]; ok {
+		return false
+	}
+
+	if f.budget != nil {
+		reason := ""
+		if !f.budget.PageAvailable() {
+			reason = "max-pages"
+		} else if !f.budget.HostAvailable(norm) {
+			reason = "max-pages-per-host"
+		}
+		if reason != "" {
+			if _, ok := f.deferred[norm]; !ok {
+				slog.Debug("budget skip", "reason", reason, "url", norm)
+				f.deferred[norm] = reason
+				f.skipped[reason]++
+			}
+			return false
+		}
+	}
+	f.visited[norm] = struct{}{}
+	f.undefer(norm)
+
+	if f.traps != nil {
+		if rule := f.traps.Check(norm); rule != "" {
+			slog.Info("trap rejected", "rule", rule, "url", norm)
+			f.skipped[rule]++
+			return false
+		}
+	}
+	if f.budget != nil {
+		f.budget.TakeHost(norm)
+	}
+
+	job := f.tmpl
+	job.URL = norm
+	job.Depth = depth
+	job.Distance = distance
+	f.queue = append(f.queue, job)
+
+	slog.Debug("enqueue", "url", norm, "depth", depth, "queue", len(f.queue), "visited", len(f.visited))
+	return true
+}
+
+// Exclude marks urls as seen without queueing them, so later Adds skip
+// them, and counts the ones not seen before as skipped for reason.
+func (f *Frontier) Exclude(reason string, urls ...string) {
+	for _, raw := range urls {
+		norm, err := NormalizeURL(f.seed, raw)
+		if err != nil || norm == "" {
+			continue
+		}
+		if _, ok := f.visited[norm]; ok {
+			continue
+		}
+		f.visited[norm] = struct{}{}
+		f.undefer(norm)
+		f.skipped[reason]++
+		slog.Debug("excluded", "reason", reason, "url", norm)
+	}
+}
+
+// undefer forgets that the budget rejected norm, which is now visited.
+func (f *Frontier) undefer(norm string) {
+	reason, ok := f.deferred[norm]
+	if !ok {
+		return
+	}
+	delete(f.deferred, norm)
+	if f.skipped[reason]--; f.skipped[reason] == 0 {
+		delete(f.skipped, reason)
+	}
+}
+
+// Requeue puts jobs back at the front of the queue without visited checks,
+// e.g. when a remote node's lease expired.
+func (f *Frontier) Requeue(jobs []CrawlJob) {
+	f.queue = append(append(make([]CrawlJob, 0, len(jobs)+len(f.queue)), jobs...), f.queue...)
+}
+
+func (f *Frontier) Len() int       { return len(f.queue) }
+func (f *Frontier) Visited() int   { return len(f.visited) }
+func (f *Frontier) Peek() CrawlJob { return f.queue[0] }
+
+func (f *Frontier) Pop() CrawlJob {
+	job := f.queue[0]
+	f.queue = f.queue[1:]
+	return job
+}
+
+// Skipped returns how many URLs Add rejected, by trap rule or budget, and
+// how many were excluded, by reason.
+func (f *Frontier) Skipped() map[string]int {
+	out := make(map[string]int, len(f.skipped))
+	for reason, n := range f.skipped {
+		out[reason] = n
+	}
+	return out
+}
+
+// Drop empties the queue and returns how many jobs were discarded.
+func (f *Frontier) Drop() int {
+	n := len(f.queue)
+	f.queue = nil
+	return n
+}
+
+// Snapshot returns the queued jobs and the visited set, e.g. for a checkpoint.
+func (f *Frontier) Snapshot() ([]CrawlJob, []string) {
+	queue := append([]CrawlJob(nil), f.queue...)
+	visited := make([]string, 0, len(f.visited))
+	for u := range f.visited {
+		visited = append(visited, u)
+	}
+	return queue, visited
+}
+
+// Restore replaces the frontier state with a snapshot. The queued jobs keep
+// their URL, depth and distance; everything else is taken from the
+// frontier's template, i.e. the current options.
+func (f *Frontier) Restore(queue []CrawlJob, visited []string) {
+	f.queue = make([]CrawlJob, 0, len(queue))
+	for _, saved := range queue {
+		job := f.tmpl
+		job.URL, job.Depth, job.Distance = saved.URL, saved.Depth, saved.Distance
+		f.queue = append(f.queue, job)
+	}
+	f.visited = make(map[string]struct{}, len(visited))
+	for _, u := range visited {
+		f.visited[u] = struct{}{}
+	}
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"maps"
+	"testing"
+)
+
+func urls(f *Frontier) []string {
+	var out []string
+	for f.Len() > 0 {
+		out = append(out, f.Pop().URL)
+	}
+	return out
+}
+
+func TestFrontierAdd(t *testing.T) {
+	const seed = "https://example.com/"
+	f := NewFrontier(seed, CrawlJob{UseJS: true}, NewTrapDetector(TrapRules{MaxPathDepth: 2}), nil)
+	tests := []struct {
+		raw   string
+		depth int
+		want  bool
+	}{
+		{seed, 2, true},
+		{"/a#top", 1, true},
+		{"https://EXAMPLE.com/a", 1, false}, // seen
+		{"/b", -1, false},                   // past max depth
+		{"mailto:x@example.com", 1, false},
+		{"/a/b/c", 1, false}, // trap
+		{"/a/b/c", 1, false}, // trap, already seen
+	}
+	for _, tt := range tests {
+		if got := f.Add(tt.raw, tt.depth, 0); got != tt.want {
+			t.Errorf("Add(%s, %d) = %v, want %v", tt.raw, tt.depth, got, tt.want)
+		}
+	}
+	if got := f.Peek(); !got.UseJS || got.Depth != 2 {
+		t.Errorf("queued job %+v, want the template's UseJS and depth 2", got)
+	}
+	if got, want := f.Skipped(), map[string]int{TrapPathDepth: 1}; !maps.Equal(got, want) {
+		t.Errorf("Skipped = %v, want %v", got, want)
+	}
+	if got := urls(f); len(got) != 2 || got[1] != "https://example.com/a" {
+		t.Errorf("queue %v, want the seed and /a", got)
+	}
+}
+
+// TestFrontierBudgetRefund checks that a URL the page budget rejected is
+// queued when it is added again after pages were returned to the budget.
+func TestFrontierBudgetRefund(t *testing.T) {
+	const seed = "https://example.com/"
+	budget := &Budget{MaxPages: 1}
+	budget.Start()
+	f := NewFrontier(seed, CrawlJob{}, nil, budget)
+	if !f.Add(seed, 1, 0) {
+		t.Fatal("Add(seed) = false")
+	}
+	f.Pop()
+	budget.UsePage()
+
+	if f.Add("/a", 0, 1) || f.Add("/a", 0, 1) {
+		t.Fatal("Add over max-pages = true")
+	}
+	if got := f.Skipped()["max-pages"]; got != 1 {
+		t.Errorf("Skipped[max-pages] = %d, want 1 for the same URL twice", got)
+	}
+
+	// the fetch is given back, e.g. its lease expired
+	budget.ReturnPages(1)
+	if !f.Add("/a", 0, 1) {
+		t.Fatal("Add after ReturnPages = false, want the rejected URL queued")
+	}
+	if got := f.Skipped(); len(got) != 0 {
+		t.Errorf("Skipped after the URL was queued = %v, want none", got)
+	}
+	if f.Add("/a", 0, 1) {
+		t.Error("Add of a queued URL = true")
+	}
+}
+
+func TestFrontierPerHostBudget(t *testing.T) {
+	const seed = "https://example.com/"
+	budget := &Budget{MaxPagesPerHost: 2}
+	budget.Start()
+	f := NewFrontier(seed, CrawlJob{}, NewTrapDetector(TrapRules{MaxPathDepth: 1}), budget)
+	for _, raw := range []string{seed, "/a/b", "/a", "/b", "https://other.example/"} {
+		f.Add(raw, 1, 0)
+	}
+	// the trap rejection does not use up a slot of the host
+	if got := urls(f); len(got) != 3 || got[1] != "https://example.com/a" || got[2] != "https://other.example/" {
+		t.Errorf("queue %v, want the seed, /a and the other host", got)
+	}
+	want := map[string]int{TrapPathDepth: 1, "max-pages-per-host": 1}
+	if got := f.Skipped(); !maps.Equal(got, want) {
+		t.Errorf("Skipped = %v, want %v", got, want)
+	}
+
+	f.Exclude("robots", "/b")
+	if got := f.Skipped(); got["max-pages-per-host"] != 0 || got["robots"] != 1 {
+		t.Errorf("Skipped after excluding the deferred URL = %v, want it counted once, as robots", got)
+	}
+}
@@ -0,0 +1,183 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// stopwords are frequent short words of the languages DetectLanguage tells
+// apart among Latin-script text.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "for", "it", "with", "as", "was", "on", "are", "this", "by", "be", "from", "or", "have", "not", "but", "which", "you", "they", "their"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "von", "sich", "des", "auf", "für", "im", "dem", "auch", "es", "wird", "sind", "oder", "wir", "ich", "aber"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "que", "dans", "pour", "qui", "pas", "sur", "au", "avec", "il", "elle", "sont", "ce", "par", "nous", "vous", "mais", "aux", "ou"},
+	"es": {"el", "la", "los", "las", "y", "que", "es", "en", "un", "una", "por", "con", "para", "del", "se", "no", "al", "lo", "como", "más", "pero", "su", "sus", "está", "son", "muy"},
+	"it": {"il", "la", "che", "di", "e", "è", "un", "una", "per", "non", "con", "del", "della", "sono", "gli", "le", "nel", "alla", "anche", "come", "ma", "più", "questo", "ha", "dei", "si"},
+	"pt": {"o", "a", "os", "as", "e", "que", "do", "da", "em", "um", "uma", "para", "com", "não", "dos", "das", "se", "por", "mais", "como", "mas", "foi", "ao", "ele", "são", "também"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "die", "er", "ook", "aan", "als", "wordt", "maar", "bij", "om", "ik", "je", "nog", "deze"},
+	"sv": {"och", "att", "det", "som", "en", "är", "av", "för", "på", "med", "till", "den", "inte", "har", "om", "ett", "var", "jag", "men", "de", "sig", "så", "kan", "från", "eller", "också"},
+	"da": {"og", "at", "det", "som", "en", "er", "af", "for", "på", "med", "til", "den", "ikke", "har", "om", "et", "var", "jeg", "men", "de", "sig", "så", "kan", "fra", "eller", "også"},
+	"no": {"og", "å", "det", "som", "en", "er", "av", "for", "på", "med", "til", "den", "ikke", "har", "om", "et", "var", "jeg", "men", "de", "seg", "så", "kan", "fra", "eller", "også"},
+	"pl": {"i", "w", "nie", "na", "się", "z", "do", "to", "że", "jest", "o", "jak", "ale", "po", "co", "tak", "za", "od", "przez", "dla", "są", "czy", "jego", "oraz", "już", "być"},
+	"cs": {"a", "v", "se", "na", "je", "že", "to", "s", "z", "do", "o", "jako", "pro", "ale", "by", "jsou", "tak", "jsem", "už", "od", "po", "které", "který", "nebo", "také", "jeho"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "olarak", "daha", "gibi", "olan", "ama", "kadar", "en", "ne", "sonra", "her", "değil", "var", "mi", "ya", "o", "ben", "şey", "göre"},
+	"id": {"yang", "dan", "di", "ini", "itu", "dengan", "untuk", "dari", "tidak", "dalam", "akan", "pada", "juga", "ke", "ada", "atau", "karena", "oleh", "saya", "kami", "mereka", "bisa", "sudah", "lebih", "telah", "adalah"},
+}
+
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for lang, words := range stopwords {
+		set := make(map[string]bool, len(words))
+		for _, w := range words {
+			set[w] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// detectSample is how much text DetectLanguage looks at.
+const detectSample = 16 << 10
+
+// DetectLanguage guesses the ISO 639-1 code of the language text is written
+// in, or returns "" when there is too little text to tell. Scripts used by
+// a single language (Greek, Hebrew, Thai, Hangul, kana, ...) decide on their
+// own; Latin-script text is scored by how many of its words are common
+// stopwords of each language.
+func DetectLanguage(text string) string {
+	if len(text) > detectSample {
+		text = text[:detectSample]
+	}
+
+	var letters, latin int
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case r < 0x250:
+			latin++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			switch r {
+			case 'і', 'ї', 'є', 'ґ', 'І', 'Ї', 'Є', 'Ґ':
+				scripts["uk"]++
+			}
+			scripts["ru"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		}
+	}
+	if letters < 20 {
+		return ""
+	}
+
+	if latin*2 < letters {
+		switch {
+		case scripts["ja"] > 0 && scripts["ja"]*10 >= scripts["zh"]:
+			// Japanese mixes kanji with kana; Chinese has no kana
+			return "ja"
+		case scripts["ru"] > 0 && scripts["uk"]*100 >= scripts["ru"]:
+			return "uk"
+		}
+		best, n := "", 0
+		for lang, c := range scripts {
+			if lang == "uk" {
+				continue
+			}
+			if lang == "zh" && scripts["ja"] > 0 {
+				c += scripts["ja"]
+			}
+			if c > n || (c == n && lang < best) {
+				best, n = lang, c
+			}
+		}
+		if n*2 >= letters-latin {
+			return best
+		}
+		return ""
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < 5 {
+		return ""
+	}
+	scores := make(map[string]int)
+	for _, w := range words {
+		for lang, set := range stopwordSets {
+			if set[w] {
+				scores[lang]++
+			}
+		}
+	}
+	best, second := "", 0
+	for lang, s := range scores {
+		switch {
+		case s > scores[best] || (s == scores[best] && lang < best):
+			if best != "" {
+				second = max(second, scores[best])
+			}
+			best = lang
+		case s > second:
+			second = s
+		}
+	}
+	// a few hits, and clearly ahead of the runner-up
+	if best == "" || scores[best] < 3 || scores[best]*10 < second*12 {
+		return ""
+	}
+	return best
+}
+
+// primaryLanguage reduces a language tag ("en-US", "pt_BR", "EN") to its
+// lowercased primary subtag, or "" when tag is not one.
+func primaryLanguage(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	for _, r := range tag {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+			return ""
+		}
+	}
+	return strings.ToLower(tag)
+}
+
+// ParseLanguages parses a comma-separated list of language codes ("en,
+// de-AT") into their lowercased primary subtags.
+func ParseLanguages(list string) ([]string, error) {
+	var langs []string
+	for _, tag := range strings.Split(list, ",") {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		lang := primaryLanguage(tag)
+		if lang == "" {
+			return nil, fmt.Errorf("invalid language code %q", strings.TrimSpace(tag))
+		}
+		langs = append(langs, lang)
+	}
+	return langs, nil
+}
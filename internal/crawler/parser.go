@@ -1,106 +1,97 @@
 package crawler
 
 import (
-	"bytes"
+	"slices"
 	"strings"
-
-	"golang.org/x/net/html"
 )
 
-func ExtractLinks(htmlBody []byte) ([]string, error) {
-	doc, err := html.Parse(bytes.NewReader(htmlBody))
-	if err != nil {
-		return nil, err
-	}
+// ImageRef is an image reference found on a page together with the
+// surrounding context that describes what it depicts.
+type ImageRef struct {
+	URL        string
+	Alt        string
+	Title      string
+	Figcaption string
+	Heading    string
+	PageTitle  string
+	Descriptor string // srcset descriptor, e.g. "2x" or "640w"
+	Sizes      string
+}
 
-	var links []string
+// Link is a page link together with where it was found.
+type Link struct {
+	URL    string
+	Source string // element the link came from: a, area, iframe, frame, link, meta, form
+	Rel    []string
+	Text   string // anchor text (including alt text of linked images) or <area alt>
+}
 
-	var walk func(*html.Node)
-	walk = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, a := range n.Attr {
-				if a.Key == "href" && strings.TrimSpace(a.Val) != "" {
-					links = append(links, strings.TrimSpace(a.Val))
-				}
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			walk(c)
-		}
-	}
+// LinkOptions toggles link sources beyond <a href>.
+type LinkOptions struct {
+	Area        bool // <area href>
+	Frames      bool // <iframe src>, <frame src>
+	LinkRel     bool // <link rel="next|prev|alternate" href>
+	MetaRefresh bool // <meta http-equiv="refresh" content="0; url=...">
+	Forms       bool // <form method="get" action>
+}
 
-	walk(doc)
-	return links, nil
+func DefaultLinkOptions() LinkOptions {
+	return LinkOptions{Area: true, Frames: true, LinkRel: true, MetaRefresh: true}
 }
 
-func ExtractImages(htmlBody []byte) ([]string, error) {
-	doc, err := html.Parse(bytes.NewReader(htmlBody))
-	if err != nil {
-		return nil, err
+// followableRel reports whether a <link> with these rel, hreflang and type
+// attributes points to a page: next and previous pages, and alternates that
+// are translations (hreflang) or HTML versions of the page. Alternate
+// stylesheets and feeds (application/rss+xml, ...) are not pages.
+func followableRel(rel, hreflang, typ string) bool {
+	rels := strings.Fields(strings.ToLower(rel))
+	if slices.Contains(rels, "stylesheet") {
+		return false
 	}
-
-	var images []string
-
-	addSrcset := func(v string) {
-		for _, u := range parseSrcset(v) {
-			if u != "" {
-				images = append(images, u)
+	for _, r := range rels {
+		switch r {
+		case "next", "prev", "previous":
+			return true
+		case "alternate":
+			mediaType, _, _ := strings.Cut(typ, ";")
+			if strings.TrimSpace(hreflang) != "" || strings.EqualFold(strings.TrimSpace(mediaType), "text/html") {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	var walk func(*html.Node)
-	walk = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
-			case "img":
-				for _, a := range n.Attr {
-					key := strings.ToLower(a.Key)
-					val := strings.TrimSpace(a.Val)
-					if val == "" {
-						continue
-					}
-					switch key {
-					case "src", "data-src", "data-original", "data-lazy-src", "data-url":
-						images = append(images, val)
-					case "srcset", "data-srcset":
-						addSrcset(val)
-					}
-				}
-
-			case "source":
-				for _, a := range n.Attr {
-					if strings.ToLower(a.Key) == "srcset" {
-						addSrcset(strings.TrimSpace(a.Val))
-					}
-				}
-
-			case "image":
-				for _, a := range n.Attr {
-					key := strings.ToLower(a.Key)
-					val := strings.TrimSpace(a.Val)
-					if val == "" {
-						continue
-					}
-					if key == "href" || key == "xlink:href" {
-						images = append(images, val)
-					}
-				}
-			}
-		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			walk(c)
-		}
+// refreshURL extracts the target of a meta refresh, e.g. "5; url=/next".
+func refreshURL(content string) string {
+	_, after, ok := strings.Cut(content, ";")
+	if !ok {
+		return ""
 	}
+	after = strings.TrimSpace(after)
+	if len(after) < 4 || !strings.EqualFold(after[:3], "url") {
+		return ""
+	}
+	after = strings.TrimSpace(after[3:])
+	after, ok = strings.CutPrefix(after, "=")
+	if !ok {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(after), `'"`)
+}
 
-	walk(doc)
-	return images, nil
+func isGetForm(method string) bool {
+	return method == "" || strings.EqualFold(method, "get")
 }
 
-func parseSrcset(v string) []string {
+type srcsetCandidate struct {
+	URL        string
+	Descriptor string
+}
+
+func parseSrcset(v string) []srcsetCandidate {
 	parts := strings.Split(v, ",")
-	out := make([]string, 0, len(parts))
+	out := make([]srcsetCandidate, 0, len(parts))
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
 		if p == "" {
@@ -108,7 +99,11 @@ func parseSrcset(v string) []string {
 		}
 		fields := strings.Fields(p)
 		if len(fields) > 0 {
-			out = append(out, fields[0])
+			c := srcsetCandidate{URL: fields[0]}
+			if len(fields) > 1 {
+				c.Descriptor = fields[1]
+			}
+			out = append(out, c)
 		}
 	}
 	return out
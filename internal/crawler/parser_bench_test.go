@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchPage builds a synthetic page with n sections, each holding a heading,
+// a figure with a responsive image, a picture element and a handful of links.
+func benchPage(n int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`<!DOCTYPE html><html><head><title>Benchmark page</title>`)
+	sb.WriteString(`<meta name="description" content="synthetic"><link rel="next" href="/page/2">`)
+	sb.WriteString(`<script>var x = "<a href='/not-a-link'>";</script></head><body>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, `<section><h2>Section %d</h2>`, i)
+		fmt.Fprintf(&sb, `<p>Lorem ipsum dolor sit amet, <a href="/article/%d">article %d</a> and <a href="https://cdn.example.com/%d">cdn</a>.</p>`, i, i, i)
+		fmt.Fprintf(&sb, `<figure><img src="/img/%d.jpg" srcset="/img/%d-1x.jpg 1x, /img/%d-2x.jpg 2x" alt="Image %d"><figcaption>Caption %d</figcaption></figure>`, i, i, i, i, i)
+		fmt.Fprintf(&sb, `<picture><source srcset="/img/%d.webp 640w, /img/%d-l.webp 1280w" sizes="50vw"><img src="/img/%d.png" alt="Pic %d"></picture>`, i, i, i, i)
+		fmt.Fprintf(&sb, `<map><area href="/area/%d"></map><iframe src="/embed/%d"></iframe></section>`, i, i)
+	}
+	sb.WriteString(`</body></html>`)
+	return []byte(sb.String())
+}
+
+func benchmarkDOM(b *testing.B, sections int) {
+	page := benchPage(sections)
+	opts := DefaultLinkOptions()
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := extractLinksDOM(page, opts); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := extractImagesDOM(page); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkTokenizer(b *testing.B, sections int) {
+	page := benchPage(sections)
+	opts := DefaultLinkOptions()
+	b.SetBytes(int64(len(page)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ExtractDocument(page, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractLinksAndImages_Small(b *testing.B) { benchmarkDOM(b, 10) }
+func BenchmarkExtractDocument_Small(b *testing.B)       { benchmarkTokenizer(b, 10) }
+
+func BenchmarkExtractLinksAndImages_Large(b *testing.B) { benchmarkDOM(b, 2000) }
+func BenchmarkExtractDocument_Large(b *testing.B)       { benchmarkTokenizer(b, 2000) }
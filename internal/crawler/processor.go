@@ -0,0 +1,157 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Page is a fetched page as seen by page processors.
+type Page struct {
+	URL      string
+	BaseURL  string      // what relative references resolve against (<base href> applied)
+	Header   http.Header // response headers; nil for pages rendered with the headless browser
+	Body     []byte
+	Doc      *Document // title, meta tags, links and images found by the built-in extraction
+	Depth    int
+	Distance int
+
+	dom    *html.Node
+	domErr error
+	parsed bool
+}
+
+// DOM returns the parsed node tree of the page. It is built on first use
+// and shared by the processors that follow.
+func (p *Page) DOM() (*html.Node, error) {
+	if !p.parsed {
+		p.dom, p.domErr = html.Parse(bytes.NewReader(p.Body))
+		p.parsed = true
+	}
+	return p.dom, p.domErr
+}
+
+// Record is a key/value pair a page processor extracted from a page.
+type Record struct {
+	Processor string
+	Key       string
+	Value     string
+}
+
+// Emitter collects what a page processor adds to a page. Relative URLs are
+// resolved against the page's base URL, and emitted links go through the
+// same domain filter as the page's own links.
+type Emitter struct {
+	processor string
+	links     []Link
+	images    []ImageRef
+	records   []Record
+}
+
+// Link adds a link to follow. An empty Source is set to the processor name.
+func (e *Emitter) Link(l Link) {
+	if l.Source == "" {
+		l.Source = e.processor
+	}
+	e.links = append(e.links, l)
+}
+
+// Image adds an image to download.
+func (e *Emitter) Image(ref ImageRef) { e.images = append(e.images, ref) }
+
+// Record adds a key/value record; records are stored with the page.
+func (e *Emitter) Record(key, value string) {
+	e.records = append(e.records, Record{Processor: e.processor, Key: key, Value: value})
+}
+
+// PageProcessor extracts extra links, images or records from fetched pages.
+// Process runs on the crawl workers, concurrently for different pages,
+// once for every page that was fetched and parsed. A returned error is
+// logged; what the processor emitted before it is kept.
+type PageProcessor interface {
+	Name() string
+	Process(ctx context.Context, page *Page, emit *Emitter) error
+}
+
+// Crawler processes crawl jobs: it fetches the page, extracts its links and
+// images, and runs the registered page processors in order. The zero value
+// runs no processors.
+type Crawler struct {
+	processors []PageProcessor
+}
+
+func NewCrawler() *Crawler {
+	return &Crawler{}
+}
+
+// Register appends page processors. It must not be called once the crawl
+// has started.
+func (c *Crawler) Register(p ...PageProcessor) {
+	c.processors = append(c.processors, p...)
+}
+
+// Processors returns the names of the registered processors, in order.
+func (c *Crawler) Processors() []string {
+	names := make([]string, len(c.processors))
+	for i, p := range c.processors {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// runProcessors passes page to every processor and returns what they
+// emitted. A processor that panics is treated like one returning an error.
+func (c *Crawler) runProcessors(ctx context.Context, page *Page) Emitter {
+	var all Emitter
+	for _, p := range c.processors {
+		emit := Emitter{processor: p.Name()}
+		if err := runProcessor(ctx, p, page, &emit); err != nil {
+			slog.Warn("page processor failed", "processor", p.Name(), "url", page.URL, "err", err)
+		}
+		all.links = append(all.links, emit.links...)
+		all.images = append(all.images, emit.images...)
+		all.records = append(all.records, emit.records...)
+	}
+	return all
+}
+
+func runProcessor(ctx context.Context, p PageProcessor, page *Page, emit *Emitter) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return p.Process(ctx, page, emit)
+}
+
+// OpenGraph is a page processor that records a page's Open Graph and
+// Twitter card meta tags (og:title, twitter:card, ...) and downloads its
+// og:image and twitter:image.
+type OpenGraph struct{}
+
+func (OpenGraph) Name() string { return "opengraph" }
+
+func (OpenGraph) Process(ctx context.Context, page *Page, emit *Emitter) error {
+	meta := page.Doc.Meta
+	for _, key := range slices.Sorted(maps.Keys(meta)) {
+		if strings.HasPrefix(key, "og:") || strings.HasPrefix(key, "twitter:") {
+			emit.Record(key, meta[key])
+		}
+	}
+	for _, key := range []string{"og:image", "og:image:url", "og:image:secure_url"} {
+		if u := meta[key]; u != "" {
+			emit.Image(ImageRef{URL: u, Alt: meta["og:image:alt"], PageTitle: page.Doc.Title})
+		}
+	}
+	if u := meta["twitter:image"]; u != "" {
+		emit.Image(ImageRef{URL: u, Alt: meta["twitter:image:alt"], PageTitle: page.Doc.Title})
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"regexp"
+
+	"GoCrawler/internal/selector"
+
+	"golang.org/x/net/html"
+)
+
+// FieldRule scrapes the field Name from the pages whose URL matches URL.
+type FieldRule struct {
+	URL      *regexp.Regexp
+	Name     string
+	Selector *selector.Selector
+	Attr     string // empty for the element's text
+	All      bool   // every match instead of the first
+}
+
+// Rules is a page processor that records the fields of every rule matching
+// the page URL, in rule order. Empty values are skipped; href and src
+// attributes are resolved against the page's base URL.
+type Rules []FieldRule
+
+func (Rules) Name() string { return "extract" }
+
+func (r Rules) Process(ctx context.Context, page *Page, emit *Emitter) error {
+	var dom *html.Node
+	for _, rule := range r {
+		if !rule.URL.MatchString(page.URL) {
+			continue
+		}
+		if dom == nil {
+			d, err := page.DOM()
+			if err != nil {
+				return err
+			}
+			dom = d
+		}
+
+		var nodes []*html.Node
+		if rule.All {
+			nodes = rule.Selector.All(dom)
+		} else if n := rule.Selector.First(dom); n != nil {
+			nodes = []*html.Node{n}
+		}
+		for _, n := range nodes {
+			if v := rule.value(page, n); v != "" {
+				emit.Record(rule.Name, v)
+			}
+		}
+	}
+	return nil
+}
+
+func (rule *FieldRule) value(page *Page, n *html.Node) string {
+	if rule.Attr == "" {
+		return selector.Text(n)
+	}
+	v, _ := selector.Attr(n, rule.Attr)
+	if rule.Attr == "href" || rule.Attr == "src" {
+		if abs, err := NormalizeURL(page.BaseURL, v); err == nil && abs != "" {
+			return abs
+		}
+	}
+	return v
+}
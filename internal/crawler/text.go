@@ -0,0 +1,213 @@
+package crawler
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTextBytes caps the main text kept per page.
+const maxTextBytes = 200 << 10
+
+// textExtractor collects the readable text of a page while ExtractDocument
+// tokenizes it. Text inside navigation, headers, footers, sidebars, forms,
+// hidden elements and the like is dropped; the rest is split into blocks at
+// block-level tags. When the page marks its content with <main>, <article>
+// or role="main", only the blocks inside it are kept. Blocks that are mostly
+// link text (menus, tag clouds, "related" lists) are dropped too.
+type textExtractor struct {
+	stack  []textFrame // open elements
+	blocks []textBlock
+	size   int
+
+	cur     strings.Builder
+	curLen  int // non-space bytes in cur
+	curLink int // ... of which inside <a>
+	curMain bool
+}
+
+type textFrame struct {
+	tag  string
+	skip bool // boilerplate: its text is not collected
+	main bool // inside <main>, <article> or role="main"
+}
+
+type textBlock struct {
+	text  string
+	main  bool
+	dense bool // mostly link text
+}
+
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "br": true, "caption": true,
+	"dd": true, "details": true, "dialog": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "summary": true, "table": true, "td": true,
+	"th": true, "tr": true, "ul": true,
+}
+
+// boilerplateTags never hold main text. header and footer are kept inside
+// <main> and <article>, where they usually hold the title and byline.
+var boilerplateTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true, "math": true,
+	"nav": true, "aside": true, "form": true, "iframe": true, "object": true, "canvas": true, "button": true,
+	"select": true, "textarea": true, "dialog": true, "menu": true,
+}
+
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true,
+	"menu": true, "menubar": true, "dialog": true, "alertdialog": true,
+}
+
+// boilerplateWords mark an element as boilerplate when they appear as a
+// word of its class or id, split on non-alphanumerics ("site-footer",
+// "nav_main").
+var boilerplateWords = map[string]bool{
+	"nav": true, "navbar": true, "navigation": true, "menu": true, "footer": true, "sidebar": true,
+	"cookie": true, "cookies": true, "consent": true, "banner": true, "breadcrumb": true, "breadcrumbs": true,
+	"comment": true, "comments": true, "share": true, "sharing": true, "social": true, "related": true,
+	"advert": true, "advertisement": true, "ads": true, "promo": true, "popup": true, "modal": true,
+	"newsletter": true, "subscribe": true, "masthead": true, "toolbar": true, "pagination": true, "skip": true,
+}
+
+func (t *textExtractor) open(tag string, attrs map[string]string, selfClosing bool) {
+	if blockTags[tag] {
+		t.flush()
+	}
+	if voidTags[tag] || selfClosing {
+		return
+	}
+	if tag == "body" {
+		t.stack = t.stack[:0] // drops a <head> that was never closed
+	}
+	var parent textFrame
+	if len(t.stack) > 0 {
+		parent = t.stack[len(t.stack)-1]
+	}
+	f := textFrame{tag: tag, skip: parent.skip, main: parent.main}
+	if !f.skip {
+		f.skip = isBoilerplate(tag, attrs, parent.main)
+	}
+	if tag == "main" || tag == "article" || strings.EqualFold(attrs["role"], "main") {
+		f.main = true
+	}
+	t.stack = append(t.stack, f)
+}
+
+func (t *textExtractor) close(tag string) {
+	if blockTags[tag] {
+		t.flush()
+	}
+	for i := len(t.stack) - 1; i >= 0; i-- {
+		if t.stack[i].tag == tag {
+			t.stack = t.stack[:i]
+			return
+		}
+	}
+}
+
+func (t *textExtractor) text(s []byte, inLink bool) {
+	if t.size >= maxTextBytes {
+		return
+	}
+	if len(t.stack) > 0 && t.stack[len(t.stack)-1].skip {
+		return
+	}
+	n := 0
+	for _, b := range s {
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' && b != '\f' {
+			n++
+		}
+	}
+	if n == 0 {
+		t.cur.WriteByte(' ')
+		return
+	}
+	if t.curLen == 0 && len(t.stack) > 0 {
+		t.curMain = t.stack[len(t.stack)-1].main
+	}
+	t.cur.Write(s)
+	t.curLen += n
+	if inLink {
+		t.curLink += n
+	}
+}
+
+func (t *textExtractor) flush() {
+	if t.curLen > 0 {
+		text := strings.Join(strings.Fields(t.cur.String()), " ")
+		t.blocks = append(t.blocks, textBlock{text: text, main: t.curMain, dense: t.curLink*2 > t.curLen})
+		t.size += len(text)
+	}
+	t.cur.Reset()
+	t.curLen, t.curLink, t.curMain = 0, 0, false
+}
+
+// String returns the kept blocks, one per line.
+func (t *textExtractor) String() string {
+	t.flush()
+	hasMain := false
+	for _, b := range t.blocks {
+		if b.main && !b.dense {
+			hasMain = true
+			break
+		}
+	}
+	var sb strings.Builder
+	for _, b := range t.blocks {
+		if b.dense || (hasMain && !b.main) {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(b.text)
+	}
+	s := sb.String()
+	if len(s) > maxTextBytes {
+		cut := maxTextBytes
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut]
+	}
+	return s
+}
+
+func isBoilerplate(tag string, attrs map[string]string, inMain bool) bool {
+	if boilerplateTags[tag] {
+		return true
+	}
+	if (tag == "header" || tag == "footer") && !inMain {
+		return true
+	}
+	if attrs == nil {
+		return false
+	}
+	if _, ok := attrs["hidden"]; ok {
+		return true
+	}
+	if strings.EqualFold(attrs["aria-hidden"], "true") || boilerplateRoles[strings.ToLower(attrs["role"])] {
+		return true
+	}
+	if style := strings.ToLower(strings.ReplaceAll(attrs["style"], " ", "")); strings.Contains(style, "display:none") {
+		return true
+	}
+	for _, key := range []string{"class", "id"} {
+		words := strings.FieldsFunc(strings.ToLower(attrs[key]), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			if boilerplateWords[w] {
+				return true
+			}
+		}
+	}
+	return false
+}
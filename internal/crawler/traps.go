@@ -0,0 +1,154 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TrapRules are heuristics against crawler traps (calendars, faceted
+// filters, session IDs in URLs). A zero limit disables that rule.
+type TrapRules struct {
+	MaxURLLength     int // characters in the normalized URL
+	MaxPathDepth     int // number of path segments
+	MaxRepeats       int // occurrences of the same path segment
+	MaxQueryVariants int // distinct query strings per path
+	MaxTemplateURLs  int // distinct URLs per path template
+}
+
+func DefaultTrapRules() TrapRules {
+	return TrapRules{
+		MaxURLLength:     2048,
+		MaxPathDepth:     20,
+		MaxRepeats:       3,
+		MaxQueryVariants: 100,
+		MaxTemplateURLs:  1000,
+	}
+}
+
+// Rule names reported by TrapDetector.Check.
+const (
+	TrapURLLength     = "url-length"
+	TrapPathDepth     = "path-depth"
+	TrapRepeatedPath  = "repeated-segment"
+	TrapQueryVariants = "query-variants"
+	TrapTemplate      = "path-template"
+)
+
+type TrapDetector struct {
+	rules TrapRules
+
+	mu        sync.Mutex
+	queries   map[string]map[string]struct{} // host+path -> distinct queries
+	templates map[string]int                 // template -> accepted URLs
+}
+
+func NewTrapDetector(rules TrapRules) *TrapDetector {
+	return &TrapDetector{
+		rules:     rules,
+		queries:   make(map[string]map[string]struct{}),
+		templates: make(map[string]int),
+	}
+}
+
+// Check reports the first rule rawURL breaks, or "" if it looks safe to
+// crawl. Accepted URLs count towards the per-path limits.
+func (d *TrapDetector) Check(rawURL string) string {
+	r := d.rules
+	if r.MaxURLLength > 0 && len(rawURL) > r.MaxURLLength {
+		return TrapURLLength
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := pathSegments(u.Path)
+	if r.MaxPathDepth > 0 && len(segments) > r.MaxPathDepth {
+		return TrapPathDepth
+	}
+	if r.MaxRepeats > 0 {
+		counts := make(map[string]int, len(segments))
+		for _, s := range segments {
+			counts[s]++
+			if counts[s] > r.MaxRepeats {
+				return TrapRepeatedPath
+			}
+		}
+	}
+
+	pathKey := u.Host + u.Path
+	query := normalizeQuery(u.RawQuery)
+	tmpl := pathTemplate(u, segments)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r.MaxQueryVariants > 0 && query != "" {
+		seen := d.queries[pathKey]
+		if _, ok := seen[query]; !ok && len(seen) >= r.MaxQueryVariants {
+			return TrapQueryVariants
+		}
+	}
+	if r.MaxTemplateURLs > 0 && d.templates[tmpl] >= r.MaxTemplateURLs {
+		return TrapTemplate
+	}
+
+	if query != "" {
+		if d.queries[pathKey] == nil {
+			d.queries[pathKey] = make(map[string]struct{})
+		}
+		d.queries[pathKey][query] = struct{}{}
+	}
+	d.templates[tmpl]++
+	return ""
+}
+
+// normalizeQuery sorts the parameters of a query string, so ?a=1&b=2 and
+// ?b=2&a=1 count as one variant. Unparseable queries are kept as they are.
+func normalizeQuery(raw string) string {
+	v, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+	return v.Encode()
+}
+
+func pathSegments(p string) []string {
+	var out []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// pathTemplate collapses segments containing digits (IDs, dates, page
+// numbers) and drops query values, so /events/2024/05?view=day and
+// /events/2025/11?view=week share one template.
+func pathTemplate(u *url.URL, segments []string) string {
+	var sb strings.Builder
+	sb.WriteString(u.Host)
+	for _, s := range segments {
+		sb.WriteByte('/')
+		if strings.ContainsAny(s, "0123456789") {
+			sb.WriteString("{n}")
+		} else {
+			sb.WriteString(s)
+		}
+	}
+
+	if u.RawQuery != "" {
+		keys := make([]string, 0, 4)
+		for k := range u.Query() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteByte('?')
+		sb.WriteString(strings.Join(keys, "&"))
+	}
+	return sb.String()
+}
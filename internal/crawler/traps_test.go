@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTrapDetector(t *testing.T) {
+	type check struct {
+		url  string
+		want string // rule broken, "" if accepted
+	}
+	tests := []struct {
+		name   string
+		rules  TrapRules
+		checks []check
+	}{
+		{"url length", TrapRules{MaxURLLength: 40}, []check{
+			{"https://example.com/short", ""},
+			{"https://example.com/" + strings.Repeat("a", 20), ""},
+			{"https://example.com/" + strings.Repeat("a", 21), TrapURLLength},
+		}},
+		{"path depth", TrapRules{MaxPathDepth: 3}, []check{
+			{"https://example.com/a/b/c", ""},
+			{"https://example.com/a/b/c/", ""},
+			{"https://example.com/a//b/c", ""},
+			{"https://example.com/a/b/c/d", TrapPathDepth},
+		}},
+		{"repeated segment", TrapRules{MaxRepeats: 2}, []check{
+			{"https://example.com/a/b/a", ""},
+			{"https://example.com/a/b/a/a", TrapRepeatedPath},
+			{"https://example.com/x/x/y/y", ""},
+		}},
+		{"query variants", TrapRules{MaxQueryVariants: 2}, []check{
+			{"https://example.com/list?a=1&b=2", ""},
+			{"https://example.com/list?b=2&a=1", ""}, // same variant
+			{"https://example.com/list?a=1&b=3", ""},
+			{"https://example.com/list?a=1&b=4", TrapQueryVariants},
+			{"https://example.com/list?b=3&a=1", ""}, // seen
+			{"https://example.com/list", ""},         // no query
+			{"https://example.com/other?a=1&b=4", ""},
+			{"https://other.example.com/list?a=1&b=4", ""},
+		}},
+		{"path template", TrapRules{MaxTemplateURLs: 2}, []check{
+			{"https://example.com/events/2024/05", ""},
+			{"https://example.com/events/2025/11", ""},
+			{"https://example.com/events/2026/01", TrapTemplate},
+			{"https://example.com/events/2026/01?view=day", ""},
+			{"https://example.com/events/2026/02?view=week", ""},
+			{"https://example.com/events/2026/03?view=month", TrapTemplate},
+			{"https://example.com/events/archive/05", ""},
+		}},
+		{"disabled", TrapRules{}, []check{
+			{"https://example.com/" + strings.Repeat("a/", 100), ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewTrapDetector(tt.rules)
+			for _, c := range tt.checks {
+				if got := d.Check(c.url); got != c.want {
+					t.Errorf("Check(%s) = %q, want %q", c.url, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrapDetectorRejectedNotCounted(t *testing.T) {
+	d := NewTrapDetector(TrapRules{MaxQueryVariants: 1, MaxTemplateURLs: 3})
+	d.Check("https://example.com/p?id=1")
+	for i := 2; i < 10; i++ {
+		u := fmt.Sprintf("https://example.com/p?id=%d", i)
+		if got := d.Check(u); got != TrapQueryVariants {
+			t.Fatalf("Check(%s) = %q, want %q", u, got, TrapQueryVariants)
+		}
+	}
+	// only the accepted URL counts towards the template
+	if got := d.Check("https://example.com/p?id=1"); got != "" {
+		t.Errorf("Check(seen variant) = %q, want accepted", got)
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"a=1&b=2", "a=1&b=2"},
+		{"b=2&a=1", "a=1&b=2"},
+		{"a=2&a=1", "a=2&a=1"},
+		{"q=hello%20world", "q=hello+world"},
+		{"", ""},
+		{"a=%zz", "a=%zz"},
+	}
+	for _, tt := range tests {
+		if got := normalizeQuery(tt.in); got != tt.want {
+			t.Errorf("normalizeQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package crawler
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/net/publicsuffix"
@@ -39,31 +41,114 @@ func NormalizeURL(baseURL, rawURL string) (string, error) {
 		u.Path = "/"
 	}
 
-	if u.Scheme != "http" && u.Scheme != "https" {
+	if base.Scheme == "file" && u.Scheme == "file" && u.Host != "" {
+		// protocol-relative link ("//cdn.example.com/x.png") in a local build
+		u.Scheme = "https"
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	case "file":
+		// local pages may link to each other, remote pages never to local files
+		if base.Scheme != "file" {
+			return "", nil
+		}
+	default:
 		return "", nil
 	}
 
 	return u.String(), nil
 }
 
-func FilterSameDomain(urls []string, domain string) []string {
-	var out []string
-	for _, raw := range urls {
+// LocalSeed recognizes a local directory, file or file:// URL as the seed of
+// a static site crawl. It returns the directory to serve and the file:// URL
+// to start from, relative to that directory.
+func LocalSeed(raw string) (seed, root string, ok bool, err error) {
+	path := raw
+	if strings.HasPrefix(raw, "file://") {
 		u, err := url.Parse(raw)
 		if err != nil {
-			continue
+			return "", "", false, err
 		}
-		host := u.Hostname()
-		if host == "" {
-			continue
+		path = u.Path
+	} else if strings.Contains(raw, "://") {
+		return "", "", false, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", false, err
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		if strings.HasPrefix(raw, "file://") {
+			return "", "", false, err
+		}
+		return "", "", false, nil // not a local path; let URL validation complain
+	}
+
+	if fi.IsDir() {
+		return "file:///", abs, true, nil
+	}
+	return "file:///" + url.PathEscape(filepath.Base(abs)), filepath.Dir(abs), true, nil
+}
+
+func IsLocalURL(raw string) bool {
+	return strings.HasPrefix(raw, "file:")
+}
+
+func FilterLocalLinks(links []Link) []Link {
+	var out []Link
+	for _, l := range links {
+		if IsLocalURL(l.URL) {
+			out = append(out, l)
 		}
-		if host == domain || strings.HasSuffix(host, "."+domain) {
+	}
+	return out
+}
+
+func FilterSameDomain(urls []string, domain string) []string {
+	var out []string
+	for _, raw := range urls {
+		if InDomain(raw, domain) {
 			out = append(out, raw)
 		}
 	}
 	return out
 }
 
+func FilterSameDomainLinks(links []Link, domain string) []Link {
+	var out []Link
+	for _, l := range links {
+		if InDomain(l.URL, domain) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func InDomain(raw, domain string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "" {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// HostOf returns the host[:port] of rawURL for per-host reporting. Local
+// file URLs report as "(local)".
+func HostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(local)"
+	}
+	return u.Host
+}
+
 func ExtractDomain(rawURL string) (string, error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -94,3 +179,40 @@ func Unique(list []string) []string {
 	}
 	return out
 }
+
+// UniqueImages drops repeated image URLs, keeping the first (usually the
+// most descriptive) occurrence of each.
+func UniqueImages(list []ImageRef) []ImageRef {
+	seen := make(map[string]struct{}, len(list))
+	out := make([]ImageRef, 0, len(list))
+
+	for _, img := range list {
+		if img.URL == "" {
+			continue
+		}
+		if _, ok := seen[img.URL]; ok {
+			continue
+		}
+		seen[img.URL] = struct{}{}
+		out = append(out, img)
+	}
+	return out
+}
+
+// UniqueLinks drops repeated link URLs, keeping the first occurrence.
+func UniqueLinks(list []Link) []Link {
+	seen := make(map[string]struct{}, len(list))
+	out := make([]Link, 0, len(list))
+
+	for _, l := range list {
+		if l.URL == "" {
+			continue
+		}
+		if _, ok := seen[l.URL]; ok {
+			continue
+		}
+		seen[l.URL] = struct{}{}
+		out = append(out, l)
+	}
+	return out
+}
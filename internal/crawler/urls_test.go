@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeURLFile(t *testing.T) {
+	tests := []struct {
+		base, raw, want string
+	}{
+		{"file:///", "about/", "file:///about/"},
+		{"file:///blog/post.html", "../about.html#team", "file:///about.html"},
+		{"file:///blog/post.html", "/img/a.png", "file:///img/a.png"},
+		{"file:///blog/post.html", "https://example.com/x", "https://example.com/x"},
+		{"file:///blog/post.html", "//cdn.example.com/x.png", "https://cdn.example.com/x.png"},
+		// resolving removes dot segments, so links cannot climb above the root
+		{"file:///blog/post.html", "../../../etc/passwd", "file:///etc/passwd"},
+		{"file:///", "file:///etc/passwd", "file:///etc/passwd"},
+		// remote pages never lead to local files
+		{"https://example.com/", "file:///etc/passwd", ""},
+		{"https://example.com/", "file:etc/passwd", ""},
+		{"http://example.com/", "FILE:///etc/passwd", ""},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeURL(tt.base, tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeURL(%q, %q) = %q, %v, want %q", tt.base, tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestLocalSeed(t *testing.T) {
+	dir := t.TempDir()
+	site := filepath.Join(dir, "site")
+	if err := os.MkdirAll(site, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	page := filepath.Join(site, "start page.html")
+	if err := os.WriteFile(page, []byte("<html></html>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		raw         string
+		seed, root  string
+		ok, wantErr bool
+	}{
+		{site, "file:///", site, true, false},
+		{page, "file:///start%20page.html", site, true, false},
+		{"file://" + site, "file:///", site, true, false},
+		{"file://" + filepath.ToSlash(page), "file:///start%20page.html", site, true, false},
+		{"file://" + filepath.Join(dir, "missing"), "", "", false, true},
+		{filepath.Join(dir, "missing"), "", "", false, false},
+		{"https://example.com/", "", "", false, false},
+		{"example.com", "", "", false, false},
+	}
+	for _, tt := range tests {
+		seed, root, ok, err := LocalSeed(tt.raw)
+		if seed != tt.seed || root != tt.root || ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("LocalSeed(%q) = %q, %q, %v, %v, want %q, %q, %v, error %v",
+				tt.raw, seed, root, ok, err, tt.seed, tt.root, tt.ok, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Alternate is a language variant of a page, declared with
+// <link rel="alternate" hreflang="..." href="...">.
+type Alternate struct {
+	Lang string `json:"lang"` // hreflang, lowercased: "de", "en-gb", "x-default"
+	URL  string `json:"url"`
+}
+
+// VariantGroup returns the key of the variant cluster a page with the given
+// alternates belongs to: the x-default variant, or else the smallest URL
+// among the page and its alternates. Every page of a site that declares its
+// variants consistently gets the same key. It is "" for pages without
+// alternates.
+func VariantGroup(pageURL string, alts []Alternate) string {
+	if len(alts) == 0 {
+		return ""
+	}
+	key := pageURL
+	for _, a := range alts {
+		if a.Lang == "x-default" {
+			return a.URL
+		}
+		if a.URL < key {
+			key = a.URL
+		}
+	}
+	return key
+}
+
+// OtherVariants returns the URLs of the alternates that are not pageURL.
+func OtherVariants(pageURL string, alts []Alternate) []string {
+	var out []string
+	for _, a := range alts {
+		if a.URL != pageURL {
+			out = append(out, a.URL)
+		}
+	}
+	return out
+}
+
+// PreferredVariant returns the URL of the variant to crawl for the language
+// tag lang: the one whose hreflang is lang, else the first (by hreflang)
+// with the same primary language ("en" picks "en-gb" before "en-us"), else
+// the x-default one. It is "" when no variant fits.
+func PreferredVariant(alts []Alternate, lang string) string {
+	sorted := slices.Clone(alts)
+	slices.SortFunc(sorted, func(a, b Alternate) int { return strings.Compare(a.Lang, b.Lang) })
+	primary := primaryLanguage(lang)
+	var fallback string
+	for _, a := range sorted {
+		if a.Lang == lang {
+			return a.URL
+		}
+		if fallback == "" && primaryLanguage(a.Lang) == primary {
+			fallback = a.URL
+		}
+	}
+	if fallback != "" {
+		return fallback
+	}
+	for _, a := range sorted {
+		if a.Lang == "x-default" {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// ParseHreflang parses the --hreflang option: "all" (or "") crawls every
+// variant and returns "", anything else must be a language tag and is
+// returned lowercased.
+func ParseHreflang(v string) (string, error) {
+	v = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(v), "_", "-"))
+	if v == "" || v == "all" {
+		return "", nil
+	}
+	if primaryLanguage(v) == "" {
+		return "", fmt.Errorf("invalid language code %q", v)
+	}
+	return v, nil
+}
+
+// VariantImages deduplicates images across the variants of a page. Sites
+// often serve the same image under each language's path (/en/img/a.png,
+// /de/img/a.png); within a variant cluster, an image whose URL is the same
+// once the page's language segment is taken out was already queued. It is
+// not safe for concurrent use.
+type VariantImages struct {
+	seen map[string]struct{}
+}
+
+func NewVariantImages() *VariantImages {
+	return &VariantImages{seen: make(map[string]struct{})}
+}
+
+// Seen reports whether imageURL, found on pageURL with the alternates alts,
+// was already seen in the variant cluster group, and records it if not.
+func (v *VariantImages) Seen(group, pageURL string, alts []Alternate, imageURL string) bool {
+	key := group + "\x00" + variantImageKey(pageURL, alts, imageURL)
+	if _, ok := v.seen[key]; ok {
+		return true
+	}
+	v.seen[key] = struct{}{}
+	return false
+}
+
+// variantImageKey returns imageURL without the language segment of pageURL:
+// the first path segment in which pageURL differs from each of its other
+// variants on the same host (/en/ against /de/ and /fr/). Without such a
+// segment, e.g. for variants on other hosts, it is imageURL itself.
+func variantImageKey(pageURL string, alts []Alternate, imageURL string) string {
+	seg := languageSegment(pageURL, alts)
+	if seg == "" {
+		return imageURL
+	}
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return imageURL
+	}
+	parts := strings.Split(u.Path, "/")
+	if i := slices.Index(parts, seg); i >= 0 {
+		u.Path = strings.Join(slices.Delete(parts, i, i+1), "/")
+	}
+	return u.String()
+}
+
+// languageSegment returns the path segment of pageURL that names its
+// language among its variants, "" if there is none.
+func languageSegment(pageURL string, alts []Alternate) string {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	segs := pathSegments(page.Path)
+	var others [][]string
+	for _, a := range alts {
+		u, err := url.Parse(a.URL)
+		if err != nil || a.URL == pageURL || u.Host != page.Host {
+			continue
+		}
+		others = append(others, pathSegments(u.Path))
+	}
+	if len(others) == 0 {
+		return ""
+	}
+	for i, seg := range segs {
+		differs := true
+		for _, o := range others {
+			if i < len(o) && o[i] == seg {
+				differs = false
+				break
+			}
+		}
+		if differs {
+			return seg
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package crawler
+
+import "testing"
+
+func TestVariantImages(t *testing.T) {
+	pathAlts := []Alternate{
+		{Lang: "en", URL: "https://example.com/en/about"},
+		{Lang: "de", URL: "https://example.com/de/ueber-uns"},
+		{Lang: "x-default", URL: "https://example.com/"},
+	}
+	hostAlts := []Alternate{
+		{Lang: "en", URL: "https://en.example.com/about"},
+		{Lang: "de", URL: "https://de.example.com/about"},
+	}
+
+	type image struct {
+		page  string
+		alts  []Alternate
+		image string
+		seen  bool // already seen in the cluster
+	}
+	tests := []struct {
+		name   string
+		images []image
+	}{
+		{"same image under each language", []image{
+			{"https://example.com/en/about", pathAlts, "https://example.com/en/img/team.png", false},
+			{"https://example.com/de/ueber-uns", pathAlts, "https://example.com/de/img/team.png", true},
+			{"https://example.com/", pathAlts, "https://example.com/img/team.png", true},
+		}},
+		{"same name in other directories", []image{
+			{"https://example.com/en/about", pathAlts, "https://example.com/en/thumbs/a.jpg", false},
+			{"https://example.com/en/about", pathAlts, "https://example.com/en/full/a.jpg", false},
+			{"https://example.com/de/ueber-uns", pathAlts, "https://example.com/de/full/a.jpg", true},
+		}},
+		{"different products named alike", []image{
+			{"https://example.com/en/about", pathAlts, "https://cdn.example.com/p/1/main.jpg", false},
+			{"https://example.com/en/about", pathAlts, "https://cdn.example.com/p/2/main.jpg", false},
+			{"https://example.com/de/ueber-uns", pathAlts, "https://cdn.example.com/p/2/main.jpg", true},
+		}},
+		{"query kept", []image{
+			{"https://example.com/en/about", pathAlts, "https://example.com/en/img/a.png?w=100", false},
+			{"https://example.com/de/ueber-uns", pathAlts, "https://example.com/de/img/a.png?w=200", false},
+		}},
+		{"language segment only removed once", []image{
+			{"https://example.com/en/about", pathAlts, "https://example.com/en/en/a.png", false},
+			{"https://example.com/de/ueber-uns", pathAlts, "https://example.com/de/a.png", false},
+		}},
+		{"variants on other hosts", []image{
+			{"https://en.example.com/about", hostAlts, "https://en.example.com/img/a.png", false},
+			{"https://de.example.com/about", hostAlts, "https://de.example.com/img/a.png", false},
+			{"https://de.example.com/about", hostAlts, "https://cdn.example.com/a.png", false},
+			{"https://en.example.com/about", hostAlts, "https://cdn.example.com/a.png", true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVariantImages()
+			for _, img := range tt.images {
+				if got := v.Seen("group", img.page, img.alts, img.image); got != img.seen {
+					t.Errorf("Seen(%s on %s) = %v, want %v", img.image, img.page, got, img.seen)
+				}
+			}
+		})
+	}
+}
+
+func TestLanguageSegment(t *testing.T) {
+	alts := []Alternate{
+		{Lang: "en", URL: "https://example.com/shop/en/shoes"},
+		{Lang: "de", URL: "https://example.com/shop/de/schuhe"},
+		{Lang: "fr", URL: "https://example.com/shop/fr/chaussures"},
+	}
+	tests := []struct {
+		page string
+		alts []Alternate
+		want string
+	}{
+		{"https://example.com/shop/en/shoes", alts, "en"},
+		{"https://example.com/shop/de/schuhe", alts, "de"},
+		{"https://example.com/shop/en/shoes", nil, ""},
+		{"https://example.com/", []Alternate{{Lang: "de", URL: "https://example.com/de/"}}, ""},
+		{"https://example.com/de/", []Alternate{{Lang: "x-default", URL: "https://example.com/"}}, "de"},
+		{"https://en.example.com/a", []Alternate{{Lang: "de", URL: "https://de.example.com/a"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := languageSegment(tt.page, tt.alts); got != tt.want {
+			t.Errorf("languageSegment(%s) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
@@ -2,25 +2,49 @@ package crawler
 
 import (
 	"context"
+	"log/slog"
+	"slices"
 	"sync"
+	"time"
 )
 
 type CrawlJob struct {
 	URL            string
-	Depth          int
+	Depth          int // remaining depth
+	Distance       int // clicks from the seed
 	FollowExternal bool
 	UseJS          bool
+	LinkSources    LinkOptions
+	Languages      []string // keep only pages in these languages; pages of unknown language are kept
+	Hreflang       string   // of pages with hreflang variants, keep only the one for this language ("" keeps all)
 }
 
 type CrawlResult struct {
-	URL       string
-	Links     []string
-	ImageURLs []string
-	Depth     int
-	Err       error
+	URL         string
+	Title       string
+	Description string
+	Lang        string      // ISO 639-1 code, "" when unknown
+	Text        string      // main readable text
+	Alternates  []Alternate // hreflang variants, resolved
+	Variant     string      // key of the variant cluster (see VariantGroup); "" without variants
+	Links       []Link
+	Images      []ImageRef
+	Records     []Record // from page processors
+	Depth       int
+	Distance    int
+	Bytes       int64
+	Fetch       time.Duration // time spent fetching the page, without the host's politeness delay
+	Skipped     string        // why the page is out of scope ("lang", "variant"); Links then holds the pages to crawl instead
+	Err         error
 }
 
 type WorkerPool struct {
+	// Limiter, when set before Start, caps the jobs processed at once per
+	// host. Jobs for a host at its limit are held back (in order) while
+	// the workers take jobs for other hosts.
+	Limiter *HostLimiter
+	freed   chan struct{} // a worker released a Limiter slot
+
 	ctx        context.Context
 	cancel     context.CancelFunc
 	maxWorkers int
@@ -44,12 +68,28 @@ func (wp *WorkerPool) Jobs() chan<- CrawlJob       { return wp.jobs }
 func (wp *WorkerPool) Results() <-chan CrawlResult { return wp.results }
 
 func (wp *WorkerPool) Start(process func(context.Context, CrawlJob) CrawlResult) {
+	jobs := wp.jobs
+	if wp.Limiter != nil {
+		ready := make(chan CrawlJob)
+		wp.freed = make(chan struct{}, 1)
+		go wp.schedule(ready)
+		jobs = ready
+	}
 	for i := 0; i < wp.maxWorkers; i++ {
 		wp.wg.Add(1)
-		go func() {
+		go func(id int) {
 			defer wp.wg.Done()
-			for job := range wp.jobs {
+			for job := range jobs {
+				start := time.Now()
 				res := process(wp.ctx, job)
+				if wp.Limiter != nil {
+					wp.Limiter.Release(HostOf(job.URL), start, res.Fetch, res.Err)
+					select {
+					case wp.freed <- struct{}{}:
+					default:
+					}
+				}
+				slog.Debug("page job done", "worker_id", id, "url", job.URL, "host", HostOf(job.URL), "depth", job.Depth, "duration", time.Since(start), "err", res.Err)
 
 				select {
 				case wp.results <- res:
@@ -57,7 +97,53 @@ func (wp *WorkerPool) Start(process func(context.Context, CrawlJob) CrawlResult)
 					return
 				}
 			}
-		}()
+		}(i)
+	}
+}
+
+// schedule passes the jobs to ready as their hosts get below their limit,
+// taking a Limiter slot for each. Past the job buffer size (or the number
+// of workers) of held-back jobs, Jobs blocks. It closes ready when the jobs
+// channel is closed and every job was passed on, or the pool is canceled.
+func (wp *WorkerPool) schedule(ready chan<- CrawlJob) {
+	defer close(ready)
+	in := wp.jobs
+	var held []CrawlJob
+	var next CrawlJob // holds a slot, waiting for a worker
+	hasNext := false
+	for {
+		if !hasNext {
+			i := slices.IndexFunc(held, func(j CrawlJob) bool { return wp.Limiter.TryAcquire(HostOf(j.URL)) })
+			if i >= 0 {
+				next, hasNext = held[i], true
+				held = slices.Delete(held, i, i+1)
+			}
+		}
+		if in == nil && !hasNext && len(held) == 0 {
+			return
+		}
+
+		var out chan<- CrawlJob
+		if hasNext {
+			out = ready
+		}
+		recv := in
+		if len(held) >= max(cap(wp.jobs), wp.maxWorkers) {
+			recv = nil
+		}
+		select {
+		case out <- next:
+			hasNext = false
+		case job, ok := <-recv:
+			if !ok {
+				in = nil
+				continue
+			}
+			held = append(held, job)
+		case <-wp.freed:
+		case <-wp.ctx.Done():
+			return
+		}
 	}
 }
 
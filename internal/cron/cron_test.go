@@ -0,0 +1,132 @@
+package cron
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Europe/Berlin for the DST tests
+)
+
+func TestNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name, spec, from, want string // want "" means never
+	}{
+		{"every minute", "* * * * *", "2026-10-19 10:07", "2026-10-19 10:08"},
+		{"strictly after", "7 10 * * *", "2026-10-19 10:07", "2026-10-20 10:07"},
+		{"seconds truncated", "8 10 * * *", "2026-10-19 10:07", "2026-10-19 10:08"},
+		{"value", "30 4 * * *", "2026-10-19 10:07", "2026-10-20 04:30"},
+		{"list", "0 6,18 * * *", "2026-10-19 10:07", "2026-10-19 18:00"},
+		{"range", "0 9-17 * * *", "2026-10-19 17:30", "2026-10-20 09:00"},
+		{"step", "*/15 * * * *", "2026-10-19 10:07", "2026-10-19 10:15"},
+		{"step wraps", "*/15 * * * *", "2026-10-19 10:50", "2026-10-19 11:00"},
+		{"range step", "10-40/15 * * * *", "2026-10-19 10:26", "2026-10-19 10:40"},
+		{"value step", "5/20 * * * *", "2026-10-19 10:26", "2026-10-19 10:45"},
+		{"month names", "0 0 1 jan,JUL *", "2026-10-19 10:07", "2027-01-01 00:00"},
+		{"month range", "0 0 1 mar-may *", "2026-10-19 10:07", "2027-03-01 00:00"},
+		{"day names", "0 0 * * sat,sun", "2026-10-19 10:07", "2026-10-24 00:00"},
+		{"day name range", "0 0 * * thu-Fri", "2026-10-19 10:07", "2026-10-22 00:00"},
+		{"7 is Sunday", "0 0 * * 7", "2026-10-19 10:07", "2026-10-25 00:00"},
+		{"0 is Sunday", "0 0 * * 0", "2026-10-19 10:07", "2026-10-25 00:00"},
+		{"day of month only", "0 0 13 * *", "2026-10-19 10:07", "2026-11-13 00:00"},
+		{"day of week only", "0 0 * * fri", "2026-10-19 10:07", "2026-10-23 00:00"},
+		{"dom or dow: dow first", "0 0 13 * fri", "2026-10-19 10:07", "2026-10-23 00:00"},
+		{"dom or dow: dom first", "0 0 13 * fri", "2026-10-10 10:07", "2026-10-13 00:00"},
+		{"dom step restricts", "0 0 */2 * mon", "2026-10-19 12:00", "2026-10-21 00:00"},
+		{"dow step restricts", "0 0 20 * */7", "2026-10-19 12:00", "2026-10-20 00:00"},
+		{"31st skips short months", "0 0 31 * *", "2026-11-01 00:00", "2026-12-31 00:00"},
+		{"leap day", "0 0 29 2 *", "2026-10-19 10:07", "2028-02-29 00:00"},
+		{"impossible date", "0 0 30 2 *", "2026-10-19 10:07", ""},
+		{"@hourly", "@hourly", "2026-10-19 10:07", "2026-10-19 11:00"},
+		{"@daily", "@daily", "2026-10-19 10:07", "2026-10-20 00:00"},
+		{"@weekly", "@weekly", "2026-10-19 10:07", "2026-10-25 00:00"},
+		{"@monthly", "@monthly", "2026-10-19 10:07", "2026-11-01 00:00"},
+		{"@yearly", "@Yearly", "2026-10-19 10:07", "2027-01-01 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.name, tt.spec, err)
+			continue
+		}
+		from := utc(tt.from).Add(30 * time.Second)
+		got := s.Next(from)
+		var want time.Time
+		if tt.want != "" {
+			want = utc(tt.want)
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: Parse(%q).Next(%s) = %s, want %s", tt.name, tt.spec, from, got, want)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04 MST", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// 2026-03-29 02:00 CET jumps to 03:00 CEST; 2026-10-25 03:00 CEST
+	// goes back to 02:00 CET
+	tests := []struct {
+		name, spec, from, want string
+	}{
+		{"skipped time does not fire", "30 2 * * *", "2026-03-28 12:00 CET", "2026-03-30 02:30 CEST"},
+		{"hourly across the gap", "0 * * * *", "2026-03-29 01:30 CET", "2026-03-29 03:00 CEST"},
+		{"daily after the gap", "0 4 * * *", "2026-03-29 01:30 CET", "2026-03-29 04:00 CEST"},
+		{"repeated time fires once", "30 2 * * *", "2026-10-25 00:00 CEST", "2026-10-25 02:30 CEST"},
+		{"repeated time not twice", "30 2 * * *", "2026-10-25 02:30 CEST", "2026-10-26 02:30 CET"},
+		{"hourly in both passes", "30 * * * *", "2026-10-25 02:30 CEST", "2026-10-25 02:30 CET"},
+		{"hourly after both passes", "30 * * * *", "2026-10-25 02:30 CET", "2026-10-25 03:30 CET"},
+		{"daily after the repeat", "0 3 * * *", "2026-10-25 02:30 CEST", "2026-10-25 03:00 CET"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, want := at(tt.from), at(tt.want)
+		if got := s.Next(from); !got.Equal(want) {
+			t.Errorf("%s: Parse(%q).Next(%s) = %s, want %s", tt.name, tt.spec, from, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@reboot",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1-x * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"* * * * funday",
+		"1,,2 * * * *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): got nil error", spec)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package export writes pages with their extracted fields and images as
+// CSV or JSON, for `crawler export` and the web server's /export.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"GoCrawler/internal/storage"
+)
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	if format == "json" {
+		return "application/json"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write writes pages to w as "csv" or "json".
+func Write(w io.Writer, format string, pages []storage.PageExport) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, pages)
+	case "json":
+		return writeJSON(w, pages)
+	}
+	return fmt.Errorf("unknown export format %q (want csv or json)", format)
+}
+
+// writeCSV writes one row per page with a column per field name; several
+// values of a field, and the image URLs and files, are joined with " | ".
+func writeCSV(w io.Writer, pages []storage.PageExport) error {
+	names := make(map[string]struct{})
+	for _, p := range pages {
+		for _, f := range p.Fields {
+			names[f.Name] = struct{}{}
+		}
+	}
+	columns := slices.Sorted(maps.Keys(names))
+
+	cw := csv.NewWriter(w)
+	cw.Write(append(append([]string{"page_url", "page_title", "crawl_id"}, columns...), "image_urls", "image_files"))
+	for _, p := range pages {
+		values := make(map[string][]string)
+		for _, f := range p.Fields {
+			values[f.Name] = append(values[f.Name], f.Value)
+		}
+		row := []string{p.URL, p.Title, strconv.FormatInt(p.CrawlID, 10)}
+		for _, c := range columns {
+			row = append(row, strings.Join(values[c], " | "))
+		}
+		var urls, files []string
+		for _, img := range p.Images {
+			urls = append(urls, img.OriginalURL)
+			files = append(files, img.SavedPath)
+		}
+		row = append(row, strings.Join(urls, " | "), strings.Join(files, " | "))
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonPage struct {
+	URL     string              `json:"url"`
+	Title   string              `json:"title"`
+	CrawlID int64               `json:"crawl_id,omitempty"`
+	Fields  map[string][]string `json:"fields"`
+	Images  []jsonImage         `json:"images"`
+}
+
+type jsonImage struct {
+	URL       string `json:"url"`
+	SavedPath string `json:"saved_path"`
+	ThumbPath string `json:"thumb_path"`
+	Filename  string `json:"filename"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Format    string `json:"format"`
+	Alt       string `json:"alt,omitempty"`
+}
+
+func writeJSON(w io.Writer, pages []storage.PageExport) error {
+	out := make([]jsonPage, 0, len(pages))
+	for _, p := range pages {
+		jp := jsonPage{URL: p.URL, Title: p.Title, CrawlID: p.CrawlID, Fields: make(map[string][]string), Images: []jsonImage{}}
+		for _, f := range p.Fields {
+			jp.Fields[f.Name] = append(jp.Fields[f.Name], f.Value)
+		}
+		for _, img := range p.Images {
+			jp.Images = append(jp.Images, jsonImage{
+				URL:       img.OriginalURL,
+				SavedPath: img.SavedPath,
+				ThumbPath: img.ThumbPath,
+				Filename:  img.Filename,
+				Width:     img.Width,
+				Height:    img.Height,
+				Format:    img.Format,
+				Alt:       img.AltText,
+			})
+		}
+		out = append(out, jp)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
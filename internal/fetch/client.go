@@ -0,0 +1,33 @@
+package fetch
+
+import (
+	"net/http"
+	"time"
+)
+
+// Transport is used by every HTTP request the crawler makes, pages and
+// images alike. Commands may wrap it (e.g. to archive responses) before
+// the crawl starts; nil means http.DefaultTransport.
+var Transport http.RoundTripper
+
+// Request defaults; commands may change them before the crawl starts.
+var (
+	UserAgent    = "GoCrawler/1.0 (+github.com/you)"
+	PageTimeout  = 10 * time.Second
+	ImageTimeout = 15 * time.Second
+)
+
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Transport,
+	}
+}
+
+// BaseTransport returns the currently installed transport, for wrapping.
+func BaseTransport() http.RoundTripper {
+	if Transport == nil {
+		return http.DefaultTransport
+	}
+	return Transport
+}
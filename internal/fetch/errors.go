@@ -0,0 +1,104 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+)
+
+// Error classes reported by Classify.
+const (
+	ClassStatus      = "http-status"
+	ClassTimeout     = "timeout"
+	ClassDNS         = "dns"
+	ClassRefused     = "connection-refused"
+	ClassReset       = "connection-reset"
+	ClassTLS         = "tls"
+	ClassCanceled    = "canceled"
+	ClassContentType = "content-type"
+	ClassOther       = "other"
+)
+
+// StatusError is returned for a response that completed with a status
+// other than 200.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return "non-200 status: " + e.Status
+}
+
+// CheckStatus returns a *StatusError unless resp is a 200.
+func CheckStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	status := resp.Status
+	if status == "" {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	return &StatusError{Code: resp.StatusCode, Status: status}
+}
+
+// ContentTypeError is returned when a response has a media type the caller
+// can't use.
+type ContentTypeError struct {
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return "unsupported image type: " + e.ContentType
+}
+
+// Classify reduces a fetch error to a coarse class, plus the HTTP status
+// code for ClassStatus.
+func Classify(err error) (class string, code int) {
+	var statusErr *StatusError
+	var ctypeErr *ContentTypeError
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var netErr net.Error
+
+	switch {
+	case err == nil:
+		return "", 0
+	case errors.As(err, &statusErr):
+		return ClassStatus, statusErr.Code
+	case errors.As(err, &ctypeErr):
+		return ClassContentType, 0
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled, 0
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout, 0
+	case errors.As(err, &dnsErr):
+		return ClassDNS, 0
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ClassRefused, 0
+	case errors.Is(err, syscall.ECONNRESET):
+		return ClassReset, 0
+	case errors.As(err, &certErr), errors.As(err, &unknownAuth), errors.As(err, &hostErr):
+		return ClassTLS, 0
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout, 0
+	}
+	return ClassOther, 0
+}
+
+// Label is the HTTP status code of a failed fetch, or its error class, for
+// use as a report key or metric label.
+func Label(err error) string {
+	class, code := Classify(err)
+	if code != 0 {
+		return strconv.Itoa(code)
+	}
+	return class
+}
@@ -0,0 +1,96 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HostRule is what HostTransport applies to requests for one host.
+type HostRule struct {
+	Delay   time.Duration     // minimum time between requests to the host
+	Headers map[string]string // set on every request, replacing existing values
+}
+
+// HostTransport applies per-host request headers and politeness delays
+// before handing requests to Next.
+type HostTransport struct {
+	Next  http.RoundTripper
+	Rules func(hostname string) HostRule
+
+	mu   sync.Mutex
+	next map[string]time.Time // earliest start of the next request per host
+}
+
+// Hosts is the installed HostTransport, if any. Fetches that do not go
+// through Transport (pages rendered by the headless browser) call its
+// Prepare to get the same delays and headers.
+var Hosts *HostTransport
+
+func (t *HostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers, err := t.Prepare(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) > 0 {
+		req = req.Clone(req.Context())
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+	return t.Next.RoundTrip(req)
+}
+
+type waitKey struct{}
+
+// WithWaitTimer returns a context under which HostTransport adds the time
+// its requests are held back by a host's delay to waited, so that callers
+// can tell the politeness wait from the round trip.
+func WithWaitTimer(ctx context.Context, waited *atomic.Int64) context.Context {
+	return context.WithValue(ctx, waitKey{}, waited)
+}
+
+// Prepare waits for the next request slot for host, as RoundTrip does, and
+// returns the headers to set on the request.
+func (t *HostTransport) Prepare(ctx context.Context, host string) (map[string]string, error) {
+	rule := t.Rules(host)
+	if rule.Delay > 0 && host != "" {
+		if err := t.wait(ctx, host, rule.Delay); err != nil {
+			return nil, err
+		}
+	}
+	return rule.Headers, nil
+}
+
+// wait reserves the next request slot for host and sleeps until it comes
+// up or ctx is canceled.
+func (t *HostTransport) wait(ctx context.Context, host string, delay time.Duration) error {
+	t.mu.Lock()
+	if t.next == nil {
+		t.next = make(map[string]time.Time)
+	}
+	now := time.Now()
+	at := now
+	if next := t.next[host]; next.After(now) {
+		at = next
+	}
+	t.next[host] = at.Add(delay)
+	t.mu.Unlock()
+
+	if at.Equal(now) {
+		return nil
+	}
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		if waited, ok := ctx.Value(waitKey{}).(*atomic.Int64); ok {
+			waited.Add(int64(time.Since(now)))
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
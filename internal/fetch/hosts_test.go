@@ -0,0 +1,74 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostTransport(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Test"))
+	}))
+	defer srv.Close()
+
+	const delay = 50 * time.Millisecond
+	ht := &HostTransport{
+		Next: http.DefaultTransport,
+		Rules: func(host string) HostRule {
+			return HostRule{Delay: delay, Headers: map[string]string{"X-Test": host}}
+		},
+	}
+	client := &http.Client{Transport: ht}
+	start := time.Now()
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	// a rendered page shares the host's slots with plain fetches
+	headers, err := ht.Prepare(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("3 requests took %s, want at least %s", elapsed, 2*delay)
+	}
+	if len(got) != 2 || got[0] != "127.0.0.1" || headers["X-Test"] != "127.0.0.1" {
+		t.Errorf("headers sent %v, prepared %v, want the host rule's", got, headers)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ht.Prepare(ctx, "127.0.0.1"); err == nil {
+		t.Error("Prepare with a canceled context waited for its slot, want an error")
+	}
+}
+
+func TestWithWaitTimer(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	ht := &HostTransport{Rules: func(string) HostRule { return HostRule{Delay: delay} }}
+	var waited atomic.Int64
+	ctx := WithWaitTimer(context.Background(), &waited)
+	for range 3 {
+		if _, err := ht.Prepare(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first request goes out at once, the next two wait a delay each
+	if got := time.Duration(waited.Load()); got < 2*delay || got > 4*delay {
+		t.Errorf("waited %s, want about %s", got, 2*delay)
+	}
+	if _, err := ht.Prepare(context.Background(), "other.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(waited.Load()); got > 4*delay {
+		t.Errorf("waited %s after a request outside the context, want it unchanged", got)
+	}
+}
@@ -0,0 +1,43 @@
+package fetch
+
+import (
+	"net/http"
+	"path"
+)
+
+// ServeDirectory makes file:// URLs resolve inside root, the way a web
+// server would serve a static site build: file:///about/ maps to
+// root/about/index.html and root-relative links stay inside root. A
+// directory without an index.html is not found rather than listed.
+func ServeDirectory(root string) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.RegisterProtocol("file", http.NewFileTransport(noListing{http.Dir(root)}))
+	Transport = t
+}
+
+// noListing hides directories that have no index.html, so that the file
+// server answers 404 instead of a generated listing.
+type noListing struct {
+	fs http.FileSystem
+}
+
+func (n noListing) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.IsDir() {
+		index, err := n.fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		index.Close()
+	}
+	return f, nil
+}
@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "site")
+	for name, body := range map[string]string{
+		"site/index.html":       "home",
+		"site/about/index.html": "about",
+		"site/assets/logo.png":  "png",
+		"secret.txt":            "secret",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := Transport
+	defer func() { Transport = saved }()
+	ServeDirectory(root)
+	client := &http.Client{Transport: Transport}
+
+	tests := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"file:///", 200, "home"},
+		{"file:///about/", 200, "about"},
+		{"file:///assets/logo.png", 200, "png"},
+		{"file:///assets/", 404, ""}, // no index.html: no listing
+		{"file:///missing.html", 404, ""},
+		{"file:///../secret.txt", 404, ""},
+		{"file:///about/../../secret.txt", 404, ""},
+		{"file:///%2e%2e/secret.txt", 404, ""},
+	}
+	for _, tt := range tests {
+		resp, err := client.Get(tt.url)
+		if err != nil {
+			t.Errorf("GET %s: %v", tt.url, err)
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || tt.status == 200 && string(body) != tt.body {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.url, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+}
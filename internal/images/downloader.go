@@ -2,8 +2,8 @@ package images
 
 import (
 	"context"
-	"errors"
 	"io"
+	"log/slog"
 	"mime"
 	"net/http"
 	"net/url"
@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"GoCrawler/internal/fetch"
 )
 
 var supported = map[string]bool{
@@ -27,12 +29,14 @@ func DownloadImage(ctx context.Context, imageURL, saveDir string) (string, strin
 		return "", "", err
 	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := fetch.NewClient(fetch.ImageTimeout)
+	start := time.Now()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
 	if err != nil {
 		return "", "", err
 	}
+	req.Header.Set("User-Agent", fetch.UserAgent)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -40,9 +44,13 @@ func DownloadImage(ctx context.Context, imageURL, saveDir string) (string, strin
 	}
 	defer resp.Body.Close()
 
+	if err := fetch.CheckStatus(resp); err != nil {
+		return "", "", err
+	}
+
 	ctype := resp.Header.Get("Content-Type")
 	if !supported[ctype] {
-		return "", "", errors.New("unsupported image type: " + ctype)
+		return "", "", &fetch.ContentTypeError{ContentType: ctype}
 	}
 
 	exts, _ := mime.ExtensionsByType(ctype)
@@ -58,16 +66,36 @@ func DownloadImage(ctx context.Context, imageURL, saveDir string) (string, strin
 
 	path := filepath.Join(saveDir, fname)
 
-	out, err := os.Create(path)
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	})
 	if err != nil {
 		return "", "", err
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	slog.Debug("image downloaded", "url", imageURL, "path", path, "content_type", ctype, "duration", time.Since(start))
+	return path, ctype, nil
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it into
+// place only once write succeeded, so an interrupted download never leaves a
+// truncated file behind.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp := path + ".part"
+	out, err := os.Create(tmp)
 	if err != nil {
-		return "", "", err
+		return err
 	}
 
-	return path, ctype, nil
+	if err := write(out); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
 }
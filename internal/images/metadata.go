@@ -8,4 +8,15 @@ type ImageMetadata struct {
 	Width       int
 	Height      int
 	Format      string
+	Size        int64 // bytes downloaded; not persisted
+
+	// Context of the page the image was found on.
+	PageURL          string
+	AltText          string
+	Title            string
+	Caption          string
+	Heading          string
+	PageTitle        string
+	SrcsetDescriptor string
+	Sizes            string
 }
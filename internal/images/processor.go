@@ -7,6 +7,7 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -40,24 +41,23 @@ func GenerateThumbnail(srcPath, thumbDir string) (string, int, int, error) {
 	thumbName := "thumb_" + fname
 	thumbPath := filepath.Join(thumbDir, thumbName)
 
-	out, err := os.Create(thumbPath)
-	if err != nil {
-		return "", 0, 0, err
-	}
-	defer out.Close()
-
+	var encode func(io.Writer) error
 	switch format {
 	case "jpeg":
-		err = jpeg.Encode(out, thumb, &jpeg.Options{Quality: 85})
+		encode = func(w io.Writer) error { return jpeg.Encode(w, thumb, &jpeg.Options{Quality: 85}) }
 	case "png":
-		err = png.Encode(out, thumb)
+		encode = func(w io.Writer) error { return png.Encode(w, thumb) }
 	case "gif":
-		err = gif.Encode(out, thumb, nil)
+		encode = func(w io.Writer) error { return gif.Encode(w, thumb, nil) }
 	default:
 		return "", 0, 0, io.ErrUnexpectedEOF
 	}
 
-	return thumbPath, newW, newH, err
+	if err := writeFileAtomic(thumbPath, encode); err != nil {
+		return "", 0, 0, err
+	}
+	slog.Debug("thumbnail written", "path", thumbPath, "width", newW, "height", newH)
+	return thumbPath, newW, newH, nil
 }
 
 func ProcessImage(ctx context.Context, url, saveDir, thumbDir string) (*ImageMetadata, error) {
@@ -67,6 +67,11 @@ func ProcessImage(ctx context.Context, url, saveDir, thumbDir string) (*ImageMet
 		return nil, err
 	}
 
+	var size int64
+	if fi, err := os.Stat(savedPath); err == nil {
+		size = fi.Size()
+	}
+
 	if ctype == "image/svg+xml" {
 		return &ImageMetadata{
 			OriginalURL: url,
@@ -76,6 +81,7 @@ func ProcessImage(ctx context.Context, url, saveDir, thumbDir string) (*ImageMet
 			Width:       0,
 			Height:      0,
 			Format:      "svg",
+			Size:        size,
 		}, nil
 	}
 
@@ -92,5 +98,6 @@ func ProcessImage(ctx context.Context, url, saveDir, thumbDir string) (*ImageMet
 		Width:       w,
 		Height:      h,
 		Format:      ctype,
+		Size:        size,
 	}, nil
 }
@@ -0,0 +1,62 @@
+// Package logging configures the process-wide log/slog logger shared by the
+// crawler and the web server.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options select the log level and output format.
+type Options struct {
+	Level  string
+	Format string
+}
+
+func (o *Options) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Level, "log-level", "info", "Minimum log level: debug, info, warn or error")
+	fs.StringVar(&o.Format, "log-format", "text", "Log output format: text or json")
+}
+
+// Apply installs the configured logger as the slog default, writing to
+// stderr.
+func (o *Options) Apply() error {
+	return o.ApplyTo(os.Stderr)
+}
+
+// ApplyTo installs the configured logger as the slog default, writing to w.
+func (o *Options) ApplyTo(w io.Writer) error {
+	logger, err := New(w, o.Level, o.Format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New returns a logger writing to w.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("--log-level: unknown level %q (want debug, info, warn or error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("--log-format: unknown format %q (want text or json)", format)
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
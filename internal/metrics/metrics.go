@@ -0,0 +1,305 @@
+// Package metrics is a minimal registry of counters, gauges and histograms
+// served in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds metrics in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the text exposition format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registry, e.g. on /metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// desc is the name, help text and label names shared by every series of a
+// metric.
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, helpEscaper.Replace(d.help), d.name, d.typ)
+}
+
+// The text exposition format escapes only these characters: backslash and
+// newline in help text, and also the double quote in label values.
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// series formats name{label="value",...} with extra appended after the
+// metric's own labels.
+func (d *desc) series(name string, values []string, extra ...string) string {
+	if len(d.labels) == 0 && len(extra) == 0 {
+		return name
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, l := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, l, labelEscaper.Replace(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if len(d.labels) > 0 || i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], labelEscaper.Replace(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// vec stores one float per label combination.
+type vec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+	order  [][]string
+}
+
+func newVec(name, help, typ string, labels []string) *vec {
+	return &vec{desc: desc{name: name, help: help, typ: typ, labels: labels}, values: make(map[string]float64)}
+}
+
+func (v *vec) update(labelValues []string, f func(float64) float64) {
+	k := v.key(labelValues)
+	v.mu.Lock()
+	old, ok := v.values[k]
+	if !ok {
+		v.order = append(v.order, slices.Clone(labelValues))
+	}
+	v.values[k] = f(old)
+	v.mu.Unlock()
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.header(w)
+	if len(v.labels) == 0 && len(v.order) == 0 {
+		fmt.Fprintf(w, "%s 0\n", v.name)
+		return
+	}
+	for _, lv := range v.order {
+		fmt.Fprintf(w, "%s %s\n", v.series(v.name, lv), formatFloat(v.values[v.key(lv)]))
+	}
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct{ *vec }
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels)}
+	r.register(name, c)
+	return c
+}
+
+// Inc adds one to the series with the given label values.
+func (c *CounterVec) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds delta, which must not be negative.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.name + " decreased")
+	}
+	c.update(labelValues, func(old float64) float64 { return old + delta })
+}
+
+// Counter is a counter without labels.
+type Counter struct{ c *CounterVec }
+
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return &Counter{r.NewCounterVec(name, help)}
+}
+
+func (c *Counter) Inc()              { c.c.Add(1) }
+func (c *Counter) Add(delta float64) { c.c.Add(delta) }
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ *vec }
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels)}
+	r.register(name, g)
+	return g
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.update(labelValues, func(float64) float64 { return value })
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.update(labelValues, func(old float64) float64 { return old + delta })
+}
+
+// Gauge is a gauge without labels.
+type Gauge struct{ g *GaugeVec }
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return &Gauge{r.NewGaugeVec(name, help)}
+}
+
+func (g *Gauge) Set(value float64) { g.g.Set(value) }
+func (g *Gauge) Add(delta float64) { g.g.Add(delta) }
+
+// gaugeFunc reads its value when scraped.
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is fn() at scrape time. fn
+// must be safe to call from the HTTP handler goroutine.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &gaugeFunc{desc: desc{name: name, help: help, typ: "gauge"}, fn: fn})
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	g.header(w)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogram
+	order   [][]string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds,
+// which must be sorted. nil means DefaultBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+	r.register(name, h)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[k]
+	if s == nil {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+		h.order = append(h.order, slices.Clone(labelValues))
+	}
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, lv := range h.order {
+		s := h.series[h.key(lv)]
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s %d\n", h.desc.series(h.name+"_bucket", lv, "le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s %d\n", h.desc.series(h.name+"_bucket", lv, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s %s\n", h.desc.series(h.name+"_sum", lv), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s %d\n", h.desc.series(h.name+"_count", lv), s.count)
+	}
+}
+
+// Histogram is a histogram without labels.
+type Histogram struct{ h *HistogramVec }
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return &Histogram{r.NewHistogramVec(name, help, buckets)}
+}
+
+func (h *Histogram) Observe(value float64) { h.h.Observe(value) }
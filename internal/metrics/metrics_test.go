@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelEscaping(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"example.com", `host="example.com"`},
+		{"bücher.de", `host="bücher.de"`},
+		{"例え.jp", `host="例え.jp"`},
+		{`say "hi"`, `host="say \"hi\""`},
+		{`C:\temp`, `host="C:\\temp"`},
+		{"a\nb", `host="a\nb"`},
+		{"tab\there", "host=\"tab\there\""},
+	}
+	for _, tt := range tests {
+		r := NewRegistry()
+		r.NewCounterVec("pages_total", "Pages.", "host").Inc(tt.value)
+		var b strings.Builder
+		r.Write(&b)
+		if want := "pages_total{" + tt.want + "} 1\n"; !strings.Contains(b.String(), want) {
+			t.Errorf("label %q: got\n%s\nwant line %q", tt.value, b.String(), want)
+		}
+	}
+}
+
+func TestHelpEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("up", "Line one\nline \\two")
+	var b strings.Builder
+	r.Write(&b)
+	if want := "# HELP up Line one\\nline \\\\two\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("got\n%s\nwant line %q", b.String(), want)
+	}
+}
@@ -0,0 +1,480 @@
+// Package selector implements the subset of CSS selectors used by the
+// extraction rules: type, universal, #id, .class and attribute selectors,
+// the :first-child, :last-child, :nth-child(n) and :nth-of-type(n)
+// pseudo-classes, the descendant, child (>), adjacent (+) and sibling (~)
+// combinators, and comma-separated groups.
+package selector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled selector group.
+type Selector struct {
+	src    string
+	chains [][]step // one per comma-separated selector
+}
+
+// step is a compound selector and the combinator joining it to the step
+// before it (0 for the first step).
+type step struct {
+	comb     byte // ' ', '>', '+', '~'
+	compound compound
+}
+
+type compound struct {
+	tag     string // "" matches any element
+	id      string
+	classes []string
+	attrs   []attrTest
+	pseudos []pseudo
+}
+
+type attrTest struct {
+	name string
+	op   string // "", "=", "~=", "^=", "$=", "*=", "|="
+	val  string
+}
+
+type pseudo struct {
+	name string // first-child, last-child, nth-child, nth-of-type
+	n    int
+}
+
+// Compile parses s.
+func Compile(s string) (*Selector, error) {
+	p := &parser{src: s}
+	sel := &Selector{src: s}
+	for {
+		chain, err := p.chain()
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", s, err)
+		}
+		sel.chains = append(sel.chains, chain)
+		p.skipSpace()
+		if p.eof() {
+			return sel, nil
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("selector %q: unexpected %q at offset %d", s, p.peek(), p.pos)
+		}
+		p.pos++
+	}
+}
+
+func (s *Selector) String() string { return s.src }
+
+// First returns the first element under root, in document order, that
+// matches, or nil.
+func (s *Selector) First(root *html.Node) *html.Node {
+	var found *html.Node
+	walk(root, func(n *html.Node) bool {
+		if s.Match(n) {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// All returns every element under root that matches, in document order.
+func (s *Selector) All(root *html.Node) []*html.Node {
+	var out []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if s.Match(n) {
+			out = append(out, n)
+		}
+		return true
+	})
+	return out
+}
+
+// Match reports whether the element n matches.
+func (s *Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, chain := range s.chains {
+		if matchChain(chain, len(chain)-1, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// walk visits the elements under root depth-first until visit returns
+// false.
+func walk(root *html.Node, visit func(*html.Node) bool) bool {
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && !visit(c) {
+			return false
+		}
+		if !walk(c, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchChain matches chain[:i+1] right to left with chain[i] on n.
+func matchChain(chain []step, i int, n *html.Node) bool {
+	if !chain[i].compound.match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch chain[i].comb {
+	case '>':
+		p := n.Parent
+		return p != nil && p.Type == html.ElementNode && matchChain(chain, i-1, p)
+	case '+':
+		s := prevElement(n)
+		return s != nil && matchChain(chain, i-1, s)
+	case '~':
+		for s := prevElement(n); s != nil; s = prevElement(s) {
+			if matchChain(chain, i-1, s) {
+				return true
+			}
+		}
+		return false
+	default: // descendant
+		for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+			if matchChain(chain, i-1, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func prevElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *compound) match(n *html.Node) bool {
+	if c.tag != "" && n.Data != c.tag {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		have := strings.Fields(attr(n, "class"))
+		for _, want := range c.classes {
+			if !contains(have, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.match(n) {
+			return false
+		}
+	}
+	for _, p := range c.pseudos {
+		if !p.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a attrTest) match(n *html.Node) bool {
+	for _, at := range n.Attr {
+		if at.Namespace != "" || at.Key != a.name {
+			continue
+		}
+		v := at.Val
+		switch a.op {
+		case "":
+			return true
+		case "=":
+			return v == a.val
+		case "~=":
+			return contains(strings.Fields(v), a.val)
+		case "^=":
+			return a.val != "" && strings.HasPrefix(v, a.val)
+		case "$=":
+			return a.val != "" && strings.HasSuffix(v, a.val)
+		case "*=":
+			return a.val != "" && strings.Contains(v, a.val)
+		case "|=":
+			return v == a.val || strings.HasPrefix(v, a.val+"-")
+		}
+	}
+	return false
+}
+
+func (p pseudo) match(n *html.Node) bool {
+	switch p.name {
+	case "first-child":
+		return prevElement(n) == nil
+	case "last-child":
+		for s := n.NextSibling; s != nil; s = s.NextSibling {
+			if s.Type == html.ElementNode {
+				return false
+			}
+		}
+		return true
+	case "nth-child", "nth-of-type":
+		i := 1
+		for s := prevElement(n); s != nil; s = prevElement(s) {
+			if p.name == "nth-child" || s.Data == n.Data {
+				i++
+			}
+		}
+		return i == p.n
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	v, _ := Attr(n, key)
+	return v
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) eof() bool  { return p.pos >= len(p.src) }
+func (p *parser) peek() byte { return p.src[p.pos] }
+
+func (p *parser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && isSpace(p.peek()) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+// chain parses compound selectors joined by combinators, up to a comma or
+// the end.
+func (p *parser) chain() ([]step, error) {
+	var chain []step
+	comb := byte(0)
+	p.skipSpace()
+	for {
+		c, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, step{comb: comb, compound: c})
+
+		space := p.skipSpace()
+		if p.eof() || p.peek() == ',' {
+			return chain, nil
+		}
+		switch p.peek() {
+		case '>', '+', '~':
+			comb = p.peek()
+			p.pos++
+			p.skipSpace()
+		default:
+			if !space {
+				return nil, fmt.Errorf("unexpected %q at offset %d", p.peek(), p.pos)
+			}
+			comb = ' '
+		}
+	}
+}
+
+func (p *parser) compound() (compound, error) {
+	var c compound
+	start := p.pos
+	if !p.eof() && p.peek() == '*' {
+		p.pos++
+	} else if name := p.ident(); name != "" {
+		c.tag = strings.ToLower(name)
+	}
+	for !p.eof() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			if c.id = p.ident(); c.id == "" {
+				return c, fmt.Errorf("missing id after '#' at offset %d", p.pos)
+			}
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, fmt.Errorf("missing class name after '.' at offset %d", p.pos)
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			a, err := p.attr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		case ':':
+			ps, err := p.pseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, ps)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("expected a selector at offset %d, got %q", p.pos, p.peek())
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, fmt.Errorf("expected a selector at offset %d", p.pos)
+	}
+	return c, nil
+}
+
+func (p *parser) ident() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// attr parses "[name]", "[name=value]" and the other operators; value may
+// be quoted.
+func (p *parser) attr() (attrTest, error) {
+	p.pos++ // [
+	p.skipSpace()
+	a := attrTest{name: strings.ToLower(p.ident())}
+	if a.name == "" {
+		return a, fmt.Errorf("missing attribute name at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if p.eof() {
+		return a, fmt.Errorf("unterminated attribute selector")
+	}
+	if p.peek() != ']' {
+		for _, op := range []string{"=", "~=", "^=", "$=", "*=", "|="} {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				a.op = op
+				p.pos += len(op)
+				break
+			}
+		}
+		if a.op == "" {
+			return a, fmt.Errorf("unknown attribute operator at offset %d", p.pos)
+		}
+		p.skipSpace()
+		if p.eof() {
+			return a, fmt.Errorf("unterminated attribute selector")
+		}
+		if q := p.peek(); q == '"' || q == '\'' {
+			end := strings.IndexByte(p.src[p.pos+1:], q)
+			if end < 0 {
+				return a, fmt.Errorf("unterminated string at offset %d", p.pos)
+			}
+			a.val = p.src[p.pos+1 : p.pos+1+end]
+			p.pos += end + 2
+		} else {
+			a.val = p.ident()
+		}
+		p.skipSpace()
+	}
+	if p.eof() || p.peek() != ']' {
+		return a, fmt.Errorf("expected ']' at offset %d", p.pos)
+	}
+	p.pos++
+	return a, nil
+}
+
+func (p *parser) pseudo() (pseudo, error) {
+	p.pos++ // :
+	ps := pseudo{name: strings.ToLower(p.ident())}
+	switch ps.name {
+	case "first-child", "last-child":
+		return ps, nil
+	case "nth-child", "nth-of-type":
+		if p.eof() || p.peek() != '(' {
+			return ps, fmt.Errorf(":%s needs an argument, e.g. :%s(2)", ps.name, ps.name)
+		}
+		end := strings.IndexByte(p.src[p.pos:], ')')
+		if end < 0 {
+			return ps, fmt.Errorf("unterminated :%s(", ps.name)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(p.src[p.pos+1 : p.pos+end]))
+		if err != nil || n < 1 {
+			return ps, fmt.Errorf(":%s supports a positive number only, got %q", ps.name, p.src[p.pos+1:p.pos+end])
+		}
+		ps.n = n
+		p.pos += end + 1
+		return ps, nil
+	}
+	return ps, fmt.Errorf("unsupported pseudo-class :%s", ps.name)
+}
+
+// blockTags separate the text of their content from the text around it.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true, "td": true, "th": true,
+	"tr": true, "ul": true,
+}
+
+// Text returns the text content of n with runs of whitespace collapsed to
+// single spaces; <script> and <style> content is skipped.
+func Text(n *html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(n.Data)
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" {
+				return
+			}
+			if blockTags[n.Data] {
+				b.WriteByte(' ')
+				defer b.WriteByte(' ')
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Attr returns the value of the attribute key of n, and whether n has it.
+func Attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,187 @@
+package selector
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const page = `<!DOCTYPE html>
+<html><body id="body">
+<div id="main" class="content wide" data-kind="article">
+  <h1 id="title">Title</h1>
+  <p id="p1" class="lead">One <a id="a1" href="https://example.com/x.pdf" hreflang="en-US">x</a></p>
+  <p id="p2">Two <span id="s1"><a id="a2" href="/y" rel="nofollow next">y</a></span></p>
+  <ul id="list"><li id="li1">1</li><li id="li2" class="odd">2</li><li id="li3">3</li></ul>
+  <span id="s2" lang="en">s</span>
+</div>
+<div id="aside"><p id="p3">Three</p></div>
+</body></html>`
+
+func parse(t *testing.T) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func ids(nodes []*html.Node) string {
+	var out []string
+	for _, n := range nodes {
+		out = append(out, attr(n, "id"))
+	}
+	return strings.Join(out, " ")
+}
+
+func mustCompile(t *testing.T, s string) *Selector {
+	t.Helper()
+	sel, err := Compile(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel
+}
+
+func TestAll(t *testing.T) {
+	doc := parse(t)
+	tests := []struct {
+		sel, want string
+	}{
+		// type, universal, id and class
+		{"p", "p1 p2 p3"},
+		{"P", "p1 p2 p3"},
+		{"#list > *", "li1 li2 li3"},
+		{"#main", "main"},
+		{"div#main", "main"},
+		{"p#main", ""},
+		{".lead", "p1"},
+		{".content.wide", "main"},
+		{".content.narrow", ""},
+		{"li.odd", "li2"},
+
+		// attributes
+		{"[data-kind]", "main"},
+		{"[ DATA-KIND = \"article\" ]", "main"},
+		{"[data-kind=art]", ""},
+		{"[rel~=next]", "a2"},
+		{"[rel~=nex]", ""},
+		{"[href^='https://']", "a1"},
+		{"[href^='']", ""},
+		{`[href$=".pdf"]`, "a1"},
+		{"[href*=example]", "a1"},
+		{"[hreflang|=en]", "a1"},
+		{"[lang|=en]", "s2"},
+		{"[hreflang|=e]", ""},
+		{"a[href][rel]", "a2"},
+
+		// combinators
+		{"#main a", "a1 a2"},
+		{"div  p", "p1 p2 p3"},
+		{"#main > p", "p1 p2"},
+		{"#main>p", "p1 p2"},
+		{"p > a", "a1"},
+		{"h1 + p", "p1"},
+		{"h1+p", "p1"},
+		{"h1 + ul", ""},
+		{"li + li", "li2 li3"},
+		{"h1 ~ p", "p1 p2"},
+		{"p ~ span", "s2"},
+		{"ul ~ p", ""},
+		{"body > div > p > span > a", "a2"},
+		{"div p ~ ul > li + li", "li2 li3"},
+
+		// pseudo-classes
+		{"li:first-child", "li1"},
+		{"li:last-child", "li3"},
+		{"#main > :first-child", "title"},
+		{"#main > :last-child", "s2"},
+		{"li:nth-child(2)", "li2"},
+		{"li:nth-child( 3 )", "li3"},
+		{"#main > :nth-child(2)", "p1"},
+		{"#main > p:nth-of-type(2)", "p2"},
+		{"#main > span:nth-of-type(1)", "s2"},
+		{"#main > :nth-of-type(1)", "title p1 list s2"},
+		{"li:first-child:last-child", ""},
+		{"li:FIRST-CHILD", "li1"},
+
+		// groups, in document order
+		{"#aside p, h1", "title p3"},
+		{"h1, h1", "title"},
+	}
+	for _, tt := range tests {
+		sel, err := Compile(tt.sel)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.sel, err)
+			continue
+		}
+		if got := ids(sel.All(doc)); got != tt.want {
+			t.Errorf("Compile(%q).All = [%s], want [%s]", tt.sel, got, tt.want)
+		}
+	}
+}
+
+func TestFirst(t *testing.T) {
+	doc := parse(t)
+	if got := ids([]*html.Node{mustCompile(t, "p").First(doc)}); got != "p1" {
+		t.Errorf("First(p) = %s, want p1", got)
+	}
+	if got := mustCompile(t, "table").First(doc); got != nil {
+		t.Errorf("First(table) = %v, want nil", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"  ",
+		",p",
+		"p,",
+		"p, ,a",
+		"> p",
+		"p >",
+		"p > > a",
+		"p !",
+		"p)",
+		"#",
+		".",
+		"p.",
+		"[",
+		"[]",
+		"[href",
+		"[href=",
+		"[href='x]",
+		"[href!=x]",
+		"[href=x y]",
+		"a:hover",
+		":not(p)",
+		"li:nth-child",
+		"li:nth-child(2",
+		"li:nth-child(0)",
+		"li:nth-child(2n+1)",
+		"li:nth-of-type(odd)",
+	} {
+		if _, err := Compile(s); err == nil {
+			t.Errorf("Compile(%q): got nil error", s)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div id="d">Hello <b>bold</b><p>para</p>  end<script>x()</script><style>p{}</style></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := mustCompile(t, "#d").First(doc)
+	if got, want := Text(d), "Hello bold para end"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+	if v, ok := Attr(d, "id"); v != "d" || !ok {
+		t.Errorf("Attr(id) = %q, %v, want d, true", v, ok)
+	}
+	if _, ok := Attr(d, "class"); ok {
+		t.Error("Attr(class) reported present")
+	}
+}
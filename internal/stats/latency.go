@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"math"
+	"time"
+)
+
+// latencyBounds are the lower bounds, in milliseconds, of the latency
+// histogram's buckets: one per millisecond up to 20ms, then 5% wider each,
+// up to an hour. Percentiles read from it are at most 5% too high.
+var latencyBounds = func() []int64 {
+	bounds := []int64{0}
+	for b := int64(1); b < int64(time.Hour/time.Millisecond); {
+		bounds = append(bounds, b)
+		b = max(b+1, int64(math.Ceil(float64(b)*1.05)))
+	}
+	return bounds
+}()
+
+// histogram counts page fetch times in the latencyBounds buckets, so that
+// the memory for percentiles does not grow with the crawl. Each bucket
+// also keeps the longest time it counted.
+type histogram struct {
+	counts []int64
+	top    []int64 // milliseconds
+	n      int64
+	max    time.Duration
+}
+
+func (h *histogram) add(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]int64, len(latencyBounds))
+		h.top = make([]int64, len(latencyBounds))
+	}
+	ms := d.Milliseconds()
+	// the last bucket whose lower bound is at most ms
+	lo, hi := 0, len(latencyBounds)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if latencyBounds[mid] <= ms {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	h.counts[lo]++
+	h.top[lo] = max(h.top[lo], ms)
+	h.n++
+	h.max = max(h.max, d)
+}
+
+// percentile returns the nearest-rank percentile in milliseconds, rounded
+// up to the longest time counted in its bucket.
+func (h *histogram) percentile(p int) int64 {
+	if h.n == 0 {
+		return 0
+	}
+	rank := max((h.n*int64(p)+99)/100, 1)
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return h.top[i]
+		}
+	}
+	return h.max.Milliseconds()
+}
@@ -0,0 +1,279 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+)
+
+// Collector accumulates crawl statistics. It is safe for concurrent use.
+type Collector struct {
+	mu          sync.Mutex
+	started     time.Time
+	hosts       map[string]*HostStats
+	statuses    map[string]int
+	imageErrors map[string]int
+	formats     map[string]int
+	skipped     map[string]int
+	latencies   histogram
+	pages       Counts
+	images      Counts
+	bytes       Bytes
+}
+
+type Counts struct {
+	OK     int `json:"ok"`
+	Failed int `json:"failed"`
+}
+
+type Bytes struct {
+	Pages  int64 `json:"pages"`
+	Images int64 `json:"images"`
+	Total  int64 `json:"total"`
+}
+
+type HostStats struct {
+	Fetched     int                `json:"fetched"`
+	Failed      int                `json:"failed"`
+	Bytes       int64              `json:"bytes"`
+	Concurrency *crawler.HostLimit `json:"concurrency,omitempty"` // with --adaptive
+}
+
+// Latency holds page fetch time percentiles in milliseconds.
+type Latency struct {
+	P50 int64 `json:"p50_ms"`
+	P90 int64 `json:"p90_ms"`
+	P99 int64 `json:"p99_ms"`
+	Max int64 `json:"max_ms"`
+}
+
+type Unfinished struct {
+	Pages  int `json:"pages"`
+	Images int `json:"images"`
+}
+
+// Report is the end-of-crawl summary, written as JSON and stored with the
+// crawl record.
+type Report struct {
+	Seed         string                `json:"seed"`
+	Outcome      string                `json:"outcome"`
+	Started      time.Time             `json:"started"`
+	Finished     time.Time             `json:"finished"`
+	Elapsed      float64               `json:"elapsed_seconds"`
+	Pages        Counts                `json:"pages"`
+	Images       Counts                `json:"images"`
+	Bytes        Bytes                 `json:"bytes"`
+	Hosts        map[string]*HostStats `json:"hosts"`
+	Statuses     map[string]int        `json:"statuses"`
+	ImageErrors  map[string]int        `json:"image_errors"`
+	ImageFormats map[string]int        `json:"image_formats"`
+	Latency      Latency               `json:"latency"`
+	Skipped      map[string]int        `json:"skipped"`
+	Unfinished   Unfinished            `json:"unfinished"`
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		started:     time.Now(),
+		hosts:       make(map[string]*HostStats),
+		statuses:    make(map[string]int),
+		imageErrors: make(map[string]int),
+		formats:     make(map[string]int),
+		skipped:     make(map[string]int),
+	}
+}
+
+// Page records a page fetch. err is the fetch or parse error, if any.
+func (c *Collector) Page(pageURL string, bytes int64, elapsed time.Duration, err error) {
+	c.PageLabel(pageURL, bytes, elapsed, fetch.Label(err))
+}
+
+// PageLabel records a page fetch whose error, if any, is given as its
+// fetch.Label, e.g. as reported by a crawler node. status is "" on success.
+func (c *Collector) PageLabel(pageURL string, bytes int64, elapsed time.Duration, status string) {
+	host := crawler.HostOf(pageURL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h := c.hosts[host]
+	if h == nil {
+		h = &HostStats{}
+		c.hosts[host] = h
+	}
+	h.Bytes += bytes
+	c.bytes.Pages += bytes
+	c.latencies.add(elapsed)
+
+	if status != "" {
+		h.Failed++
+		c.pages.Failed++
+		c.statuses[status]++
+		return
+	}
+	h.Fetched++
+	c.pages.OK++
+	c.statuses["200"]++
+}
+
+// Image records a finished image job. format and bytes are ignored on error.
+func (c *Collector) Image(format string, bytes int64, err error) {
+	c.ImageLabel(format, bytes, fetch.Label(err))
+}
+
+// ImageLabel is Image with the error given as its fetch.Label, "" on
+// success.
+func (c *Collector) ImageLabel(format string, bytes int64, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if status != "" {
+		c.images.Failed++
+		c.imageErrors[status]++
+		return
+	}
+	c.images.OK++
+	c.bytes.Images += bytes
+	c.formats[format]++
+}
+
+// Skip counts n URLs that were not crawled for reason.
+func (c *Collector) Skip(reason string, n int) {
+	if n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.skipped[reason] += n
+	c.mu.Unlock()
+}
+
+// Concurrency records the per-host concurrency limits of an adaptive crawl.
+func (c *Collector) Concurrency(limits map[string]crawler.HostLimit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for host, l := range limits {
+		h := c.hosts[host]
+		if h == nil {
+			h = &HostStats{}
+			c.hosts[host] = h
+		}
+		h.Concurrency = &l
+	}
+}
+
+// Finish builds the report for a crawl of seed that ended with outcome.
+func (c *Collector) Finish(seed, outcome string, unfinished Unfinished) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	r := &Report{
+		Seed:         seed,
+		Outcome:      outcome,
+		Started:      c.started,
+		Finished:     now,
+		Elapsed:      now.Sub(c.started).Round(time.Millisecond).Seconds(),
+		Pages:        c.pages,
+		Images:       c.images,
+		Bytes:        c.bytes,
+		Hosts:        make(map[string]*HostStats, len(c.hosts)),
+		Statuses:     maps.Clone(c.statuses),
+		ImageErrors:  maps.Clone(c.imageErrors),
+		ImageFormats: maps.Clone(c.formats),
+		Skipped:      maps.Clone(c.skipped),
+		Unfinished:   unfinished,
+	}
+	r.Bytes.Total = r.Bytes.Pages + r.Bytes.Images
+	for host, h := range c.hosts {
+		cp := *h
+		r.Hosts[host] = &cp
+	}
+
+	r.Latency = Latency{
+		P50: c.latencies.percentile(50),
+		P90: c.latencies.percentile(90),
+		P99: c.latencies.percentile(99),
+		Max: c.latencies.max.Milliseconds(),
+	}
+	return r
+}
+
+// WriteFile writes the report as indented JSON.
+func (r *Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Print writes the report as human-readable tables.
+func (r *Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "=== SUMMARY ===")
+	fmt.Fprintf(tw, "seed\t%s\n", r.Seed)
+	fmt.Fprintf(tw, "outcome\t%s\n", r.Outcome)
+	fmt.Fprintf(tw, "elapsed\t%s\n", time.Duration(r.Elapsed*float64(time.Second)).Round(time.Second))
+	fmt.Fprintf(tw, "pages\tok %d\tfailed %d\n", r.Pages.OK, r.Pages.Failed)
+	fmt.Fprintf(tw, "images\tok %d\tfailed %d\n", r.Images.OK, r.Images.Failed)
+	fmt.Fprintf(tw, "bytes\tpages %d\timages %d\ttotal %d\n", r.Bytes.Pages, r.Bytes.Images, r.Bytes.Total)
+	fmt.Fprintf(tw, "fetch latency\tp50 %dms\tp90 %dms\tp99 %dms\tmax %dms\n", r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+	fmt.Fprintf(tw, "unfinished\tpages %d\timages %d\n", r.Unfinished.Pages, r.Unfinished.Images)
+
+	if len(r.Hosts) > 0 {
+		adaptive := false
+		for _, h := range r.Hosts {
+			adaptive = adaptive || h.Concurrency != nil
+		}
+		if adaptive {
+			fmt.Fprintln(tw, "\nHOST\tFETCHED\tFAILED\tBYTES\tLIMIT\tRANGE\tBACKOFFS")
+		} else {
+			fmt.Fprintln(tw, "\nHOST\tFETCHED\tFAILED\tBYTES")
+		}
+		for _, host := range sortedKeys(r.Hosts) {
+			h := r.Hosts[host]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d", host, h.Fetched, h.Failed, h.Bytes)
+			if l := h.Concurrency; l != nil {
+				fmt.Fprintf(tw, "\t%d\t%d-%d\t%d", l.Limit, l.Lowest, l.Highest, l.Backoffs)
+			}
+			fmt.Fprintln(tw)
+		}
+	}
+	printCounts(tw, "STATUS", r.Statuses)
+	printCounts(tw, "IMAGE ERROR", r.ImageErrors)
+	printCounts(tw, "IMAGE FORMAT", r.ImageFormats)
+	printCounts(tw, "SKIPPED", r.Skipped)
+	fmt.Fprintln(tw, "===============")
+	tw.Flush()
+}
+
+func printCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\tCOUNT\n", title)
+	keys := sortedKeys(counts)
+	sort.SliceStable(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%d\n", k, counts[k])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"GoCrawler/internal/crawler"
+	"GoCrawler/internal/fetch"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	var h histogram
+	if got := h.percentile(50); got != 0 {
+		t.Errorf("percentile of no fetches = %d, want 0", got)
+	}
+	for ms := 1; ms <= 10; ms++ {
+		h.add(time.Duration(ms)*time.Millisecond + 500*time.Microsecond)
+	}
+	tests := []struct {
+		p    int
+		want int64
+	}{
+		{0, 1}, {10, 1}, {11, 2}, {50, 5}, {90, 9}, {91, 10}, {99, 10}, {100, 10},
+	}
+	for _, tt := range tests {
+		if got := h.percentile(tt.p); got != tt.want {
+			t.Errorf("percentile(%d) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if got := h.max; got != 10500*time.Microsecond {
+		t.Errorf("max = %s, want 10.5ms", got)
+	}
+}
+
+func TestHistogramBounded(t *testing.T) {
+	var h histogram
+	for i := range 100000 {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+	h.add(3 * time.Hour)
+	if len(h.counts) != len(latencyBounds) {
+		t.Fatalf("%d buckets after 100001 fetches, want %d", len(h.counts), len(latencyBounds))
+	}
+	for _, p := range []int{50, 90, 99} {
+		want := float64(p*1000 - 1) // of 0..99999ms
+		got := float64(h.percentile(p))
+		if got < want || got > want*1.05 {
+			t.Errorf("percentile(%d) = %.0f, want within 5%% above %.0f", p, got, want)
+		}
+	}
+	if got := h.percentile(100); got != (3 * time.Hour).Milliseconds() {
+		t.Errorf("percentile(100) = %d, want the 3h maximum", got)
+	}
+	for i := 1; i < len(latencyBounds); i++ {
+		if latencyBounds[i] <= latencyBounds[i-1] {
+			t.Fatalf("latencyBounds not increasing at %d: %v", i, latencyBounds[i-1:i+1])
+		}
+	}
+}
+
+func TestFinish(t *testing.T) {
+	c := NewCollector()
+	c.Page("https://a.example/1", 100, 10*time.Millisecond, nil)
+	c.Page("https://a.example/2", 50, 20*time.Millisecond, &fetch.StatusError{Code: 404, Status: "404 Not Found"})
+	c.Page("https://b.example/", 30, 30*time.Millisecond, nil)
+	c.Page("file:///index.html", 5, 0, nil)
+	c.Image("png", 1000, nil)
+	c.Image("jpeg", 2000, nil)
+	c.Image("", 99, errors.New("decode failed"))
+	c.Skip("max-pages", 3)
+	c.Skip("robots", 0)
+	c.Concurrency(map[string]crawler.HostLimit{"b.example": {Limit: 4, Lowest: 2, Highest: 6, Backoffs: 1}})
+
+	r := c.Finish("https://a.example/", "completed", Unfinished{Pages: 2})
+	if r.Pages != (Counts{OK: 3, Failed: 1}) || r.Images != (Counts{OK: 2, Failed: 1}) {
+		t.Errorf("pages %+v, images %+v, want 3/1 and 2/1", r.Pages, r.Images)
+	}
+	if r.Bytes != (Bytes{Pages: 185, Images: 3000, Total: 3185}) {
+		t.Errorf("bytes %+v, want pages 185, images 3000, total 3185", r.Bytes)
+	}
+	if a := r.Hosts["a.example"]; a == nil || a.Fetched != 1 || a.Failed != 1 || a.Bytes != 150 || a.Concurrency != nil {
+		t.Errorf("hosts[a.example] = %+v, want 1 fetched, 1 failed, 150 bytes", a)
+	}
+	if b := r.Hosts["b.example"]; b == nil || b.Concurrency == nil || b.Concurrency.Limit != 4 {
+		t.Errorf("hosts[b.example] = %+v, want the concurrency limit", b)
+	}
+	if r.Hosts["(local)"] == nil {
+		t.Errorf("hosts %v, want file URLs under (local)", r.Hosts)
+	}
+	if want := map[string]int{"200": 3, "404": 1}; !maps.Equal(r.Statuses, want) {
+		t.Errorf("statuses %v, want %v", r.Statuses, want)
+	}
+	if want := map[string]int{"png": 1, "jpeg": 1}; !maps.Equal(r.ImageFormats, want) {
+		t.Errorf("image formats %v, want %v", r.ImageFormats, want)
+	}
+	if want := map[string]int{"max-pages": 3}; !maps.Equal(r.Skipped, want) {
+		t.Errorf("skipped %v, want %v", r.Skipped, want)
+	}
+	if want := (Latency{P50: 10, P90: 30, P99: 30, Max: 30}); r.Latency != want {
+		t.Errorf("latency %+v, want %+v", r.Latency, want)
+	}
+	if r.Unfinished.Pages != 2 || r.Outcome != "completed" || r.Seed != "https://a.example/" {
+		t.Errorf("report %+v, want the seed, outcome and unfinished counts passed in", r)
+	}
+
+	// the report is a copy: later fetches do not change it
+	c.Page("https://a.example/3", 1, time.Second, nil)
+	if r.Hosts["a.example"].Fetched != 1 || r.Pages.OK != 3 {
+		t.Error("Finish's report changed with a later Page")
+	}
+
+	var out strings.Builder
+	r.Print(&out)
+	for _, want := range []string{"p50 10ms", "LIMIT", "b.example", "2-6", "STATUS", "IMAGE ERROR", "SKIPPED", "max-pages"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Print output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
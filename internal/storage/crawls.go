@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CrawlRunning is the status of a crawl that has not finished. A finished
+// crawl's status is how it ended: "complete", "timeout", "interrupted" or
+// "failed".
+const CrawlRunning = "running"
+
+// ErrNoCrawl is returned for a crawl id that does not exist.
+var ErrNoCrawl = errors.New("no such crawl")
+
+// CrawlRecord is one crawl run. Options and Stats are JSON documents; Stats
+// is nil until the crawl finishes, as is FinishedAt.
+type CrawlRecord struct {
+	ID         int64
+	Name       string
+	Seed       string
+	Options    []byte
+	Status     string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	Stats      []byte
+
+	// Filled in by ListCrawls.
+	Pages  int
+	Images int
+}
+
+// CrawlFilter restricts ListCrawls. Empty fields match everything; Name
+// and Seed match substrings.
+type CrawlFilter struct {
+	Name   string
+	Seed   string
+	Status string
+}
+
+type CrawlRepository struct {
+	db *MySQLStorage
+}
+
+func NewCrawlRepository(store *MySQLStorage) *CrawlRepository {
+	return &CrawlRepository{db: store}
+}
+
+// StartCrawl records a new running crawl and returns its id.
+func (repo *CrawlRepository) StartCrawl(ctx context.Context, name, seed string, options []byte, startedAt time.Time) (int64, error) {
+	query := `
+        INSERT INTO crawls (name, seed, options, status, started_at)
+        VALUES (?, ?, ?, ?, ?)
+    `
+
+	res, err := repo.db.DB.ExecContext(ctx, query, name, seed, string(options), CrawlRunning, startedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// ResumeCrawl marks a stopped crawl as running again.
+func (repo *CrawlRepository) ResumeCrawl(ctx context.Context, id int64) error {
+	res, err := repo.db.DB.ExecContext(ctx,
+		"UPDATE crawls SET status = ?, finished_at = NULL WHERE id = ?", CrawlRunning, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
+
+// FinishCrawl stores the outcome and statistics report of a crawl.
+func (repo *CrawlRepository) FinishCrawl(ctx context.Context, id int64, status string, finishedAt time.Time, stats []byte) error {
+	var statsArg any
+	if stats != nil {
+		statsArg = string(stats)
+	}
+	res, err := repo.db.DB.ExecContext(ctx,
+		"UPDATE crawls SET status = ?, finished_at = ?, stats = ? WHERE id = ?", status, finishedAt, statsArg, id)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
+
+// ListCrawls returns the crawls matching f, newest first, with the number
+// of pages and images each one stored.
+func (repo *CrawlRepository) ListCrawls(ctx context.Context, f CrawlFilter) ([]CrawlRecord, error) {
+	query := `
+        SELECT c.id, c.name, c.seed, c.options, c.status, c.started_at, c.finished_at,
+               (SELECT COUNT(*) FROM pages p WHERE p.crawl_id = c.id),
+               (SELECT COUNT(*) FROM images i WHERE i.crawl_id = c.id)
+        FROM crawls c WHERE 1=1`
+	args := []interface{}{}
+
+	if f.Name != "" {
+		query += " AND c.name LIKE ?"
+		args = append(args, "%"+f.Name+"%")
+	}
+	if f.Seed != "" {
+		query += " AND c.seed LIKE ?"
+		args = append(args, "%"+f.Seed+"%")
+	}
+	if f.Status != "" {
+		query += " AND c.status = ?"
+		args = append(args, f.Status)
+	}
+	query += " ORDER BY c.id DESC"
+
+	rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CrawlRecord
+	for rows.Next() {
+		var c CrawlRecord
+		var options string
+		var finished sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Seed, &options, &c.Status, &c.StartedAt, &finished, &c.Pages, &c.Images); err != nil {
+			return nil, err
+		}
+		c.Options = []byte(options)
+		if finished.Valid {
+			c.FinishedAt = &finished.Time
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// DeleteCrawl removes a crawl with the images and pages (and their links
+// and fields) it stored. It returns the image and thumbnail files no remaining image
+// row refers to, for the caller to delete.
+func (repo *CrawlRepository) DeleteCrawl(ctx context.Context, id int64) ([]string, error) {
+	tx, err := repo.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT saved_path, thumb_path FROM images i
+        WHERE i.crawl_id = ? AND NOT EXISTS (
+            SELECT 1 FROM images o WHERE o.saved_path = i.saved_path AND (o.crawl_id IS NULL OR o.crawl_id <> i.crawl_id)
+        )
+    `, id)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	var files []string
+	for rows.Next() {
+		var saved, thumb string
+		if err := rows.Scan(&saved, &thumb); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for _, p := range []string{saved, thumb} {
+			if _, ok := seen[p]; !ok && p != "" {
+				seen[p] = struct{}{}
+				files = append(files, p)
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		"DELETE FROM images WHERE crawl_id = ?",
+		"DELETE l FROM links l JOIN pages p ON p.id = l.source_page_id WHERE p.crawl_id = ?",
+		"DELETE f FROM page_fields f JOIN pages p ON p.id = f.page_id WHERE p.crawl_id = ?",
+		"DELETE FROM pages WHERE crawl_id = ?",
+	}
+	for _, q := range statements {
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return nil, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM crawls WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectRow(res); err != nil {
+		return nil, err
+	}
+	return files, tx.Commit()
+}
+
+// expectRow turns a statement that matched no crawl into ErrNoCrawl.
+func expectRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoCrawl
+	}
+	return nil
+}
+
+// nullID stores a zero crawl id as NULL.
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
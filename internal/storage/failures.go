@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+)
+
+// Failure is a page or image that could not be fetched.
+type Failure struct {
+	URL        string
+	Kind       string // "page" or "image"
+	StatusCode int    // 0 unless ErrorClass is an HTTP status
+	ErrorClass string
+	Error      string
+}
+
+// BrokenLink is a failed target together with one page that references it.
+type BrokenLink struct {
+	Failure
+	ReferrerURL   string
+	ReferrerTitle string
+	AnchorText    string
+	Element       string
+}
+
+// RecordFailure stores or refreshes the most recent failure for a URL.
+func (repo *PageRepository) RecordFailure(ctx context.Context, f Failure) error {
+	query := `
+        INSERT INTO failures (url, url_hash, kind, status_code, error_class, error)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE kind = VALUES(kind), status_code = VALUES(status_code),
+            error_class = VALUES(error_class), error = VALUES(error), failed_at = CURRENT_TIMESTAMP
+    `
+
+	_, err := repo.db.DB.ExecContext(ctx, query, f.URL, URLHash(f.URL), f.Kind, f.StatusCode, f.ErrorClass, f.Error)
+	return err
+}
+
+// ClearFailure forgets an earlier failure once the URL has been fetched
+// successfully.
+func (repo *PageRepository) ClearFailure(ctx context.Context, url string) error {
+	_, err := repo.db.DB.ExecContext(ctx, "DELETE FROM failures WHERE url_hash = ?", URLHash(url))
+	return err
+}
+
+// BrokenLinks lists every failed target with the pages linking to it,
+// ordered by referrer so the result can be grouped page by page. kind
+// restricts the result to "page" or "image" failures when non-empty.
+func (repo *PageRepository) BrokenLinks(ctx context.Context, kind string) ([]BrokenLink, error) {
+	query := `
+        SELECT f.url, f.kind, f.status_code, f.error_class, f.error,
+               COALESCE(p.url, ''), COALESCE(p.title, ''), COALESCE(l.anchor_text, ''), COALESCE(l.element, '')
+        FROM failures f
+        LEFT JOIN links l ON l.target_hash = f.url_hash
+        LEFT JOIN pages p ON p.id = l.source_page_id
+    `
+	var args []interface{}
+	if kind != "" {
+		query += " WHERE f.kind = ?"
+		args = append(args, kind)
+	}
+	query += " ORDER BY p.url, f.url"
+
+	rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BrokenLink
+	for rows.Next() {
+		var b BrokenLink
+		if err := rows.Scan(&b.URL, &b.Kind, &b.StatusCode, &b.ErrorClass, &b.Error,
+			&b.ReferrerURL, &b.ReferrerTitle, &b.AnchorText, &b.Element); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
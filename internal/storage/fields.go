@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"GoCrawler/internal/images"
+)
+
+// PageField is a key/value record a page processor extracted from a page,
+// as stored in the page_fields table.
+type PageField struct {
+	Source string // processor that emitted it
+	Name   string
+	Value  string
+}
+
+// SavePageFields stores the records extracted from a page, replacing any
+// stored for it by an earlier visit.
+func (repo *PageRepository) SavePageFields(ctx context.Context, pageID int64, fields []PageField) error {
+	if len(fields) == 0 {
+		_, err := repo.db.DB.ExecContext(ctx, "DELETE FROM page_fields WHERE page_id = ?", pageID)
+		return err
+	}
+
+	tx, err := repo.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM page_fields WHERE page_id = ?", pageID); err != nil {
+		return err
+	}
+
+	const batch = 200
+	for start := 0; start < len(fields); start += batch {
+		end := min(start+batch, len(fields))
+
+		query := "INSERT INTO page_fields (page_id, source, name, value) VALUES "
+		args := make([]interface{}, 0, (end-start)*4)
+		for i, f := range fields[start:end] {
+			if i > 0 {
+				query += ", "
+			}
+			query += "(?, ?, ?, ?)"
+			args = append(args, pageID, f.Source, f.Name, f.Value)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FieldFilter restricts ExportPages. Empty fields match everything; Value
+// matches substrings of the values of field Name (of any field when Name
+// is empty).
+type FieldFilter struct {
+	CrawlID int64
+	Name    string
+	Value   string
+}
+
+// PageExport is a page with the fields extracted from it and the images
+// found on it.
+type PageExport struct {
+	URL     string
+	Title   string
+	CrawlID int64
+	Fields  []PageField
+	Images  []images.ImageMetadata
+}
+
+// fieldCondition is the SQL condition, on page_fields aliased f, for the
+// name and value of f.
+func fieldCondition(f FieldFilter) (string, []interface{}) {
+	cond := ""
+	var args []interface{}
+	if f.Name != "" {
+		cond += " AND f.name = ?"
+		args = append(args, f.Name)
+	}
+	if f.Value != "" {
+		cond += " AND f.value LIKE ?"
+		args = append(args, "%"+f.Value+"%")
+	}
+	return cond, args
+}
+
+// ExportPages returns the pages with fields matching f, in crawl order,
+// with all their fields and the images found on them (only those stored
+// by crawl f.CrawlID when set).
+func (repo *PageRepository) ExportPages(ctx context.Context, f FieldFilter) ([]PageExport, error) {
+	cond, args := fieldCondition(f)
+	query := `
+        SELECT p.id, p.url, p.title, COALESCE(p.crawl_id, 0) FROM pages p
+        WHERE EXISTS (SELECT 1 FROM page_fields f WHERE f.page_id = p.id` + cond + `)`
+	if f.CrawlID != 0 {
+		query += " AND p.crawl_id = ?"
+		args = append(args, f.CrawlID)
+	}
+	query += " ORDER BY p.id"
+
+	rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var out []PageExport
+	byID := make(map[int64]int)
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		var p PageExport
+		if err := rows.Scan(&id, &p.URL, &p.Title, &p.CrawlID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byID[id] = len(out)
+		ids = append(ids, id)
+		out = append(out, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(ids); start += 500 {
+		chunk := ids[start:min(start+500, len(ids))]
+		query := `SELECT page_id, source, name, value FROM page_fields
+                  WHERE page_id IN (?` + strings.Repeat(", ?", len(chunk)-1) + `) ORDER BY id`
+		rows, err := repo.db.DB.QueryContext(ctx, query, chunk...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			var pf PageField
+			if err := rows.Scan(&id, &pf.Source, &pf.Name, &pf.Value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p := &out[byID[id]]
+			p.Fields = append(p.Fields, pf)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	urls := make([]string, len(out))
+	for i, p := range out {
+		urls[i] = p.URL
+	}
+	imgs, err := repo.PageImages(ctx, urls, f.CrawlID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Images = imgs[out[i].URL]
+	}
+	return out, nil
+}
+
+// PageFields returns the fields stored for the given page URLs, keyed by
+// URL.
+func (repo *PageRepository) PageFields(ctx context.Context, urls []string) (map[string][]PageField, error) {
+	out := make(map[string][]PageField)
+	for start := 0; start < len(urls); start += 500 {
+		chunk := urls[start:min(start+500, len(urls))]
+		query := `SELECT p.url, f.source, f.name, f.value FROM page_fields f JOIN pages p ON p.id = f.page_id
+                  WHERE p.url_hash IN (?` + strings.Repeat(", ?", len(chunk)-1) + `) ORDER BY f.id`
+		args := make([]interface{}, len(chunk))
+		for i, u := range chunk {
+			args[i] = URLHash(u)
+		}
+
+		rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var u string
+			var pf PageField
+			if err := rows.Scan(&u, &pf.Source, &pf.Name, &pf.Value); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out[u] = append(out[u], pf)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// FieldNames lists the distinct stored field names.
+func (repo *PageRepository) FieldNames(ctx context.Context) ([]string, error) {
+	rows, err := repo.db.DB.QueryContext(ctx, "SELECT DISTINCT name FROM page_fields ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+// PageImages returns the images found on the given page URLs, keyed by page
+// URL, each image once per page. A non-zero crawlID keeps only the images
+// stored by that crawl.
+func (repo *PageRepository) PageImages(ctx context.Context, urls []string, crawlID int64) (map[string][]images.ImageMetadata, error) {
+	out := make(map[string][]images.ImageMetadata)
+	for start := 0; start < len(urls); start += 500 {
+		chunk := urls[start:min(start+500, len(urls))]
+		query := `SELECT page_url, original_url, saved_path, thumb_path, filename, width, height, format, alt_text
+                  FROM images WHERE page_url IN (?` + strings.Repeat(", ?", len(chunk)-1) + `)`
+		args := make([]interface{}, 0, len(chunk)+1)
+		for _, u := range chunk {
+			args = append(args, u)
+		}
+		if crawlID != 0 {
+			query += " AND crawl_id = ?"
+			args = append(args, crawlID)
+		}
+		query += " ORDER BY id"
+
+		rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var m images.ImageMetadata
+			if err := rows.Scan(&m.PageURL, &m.OriginalURL, &m.SavedPath, &m.ThumbPath, &m.Filename, &m.Width, &m.Height, &m.Format, &m.AltText); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			list := out[m.PageURL]
+			if !slices.ContainsFunc(list, func(o images.ImageMetadata) bool { return o.OriginalURL == m.OriginalURL }) {
+				out[m.PageURL] = append(list, m)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
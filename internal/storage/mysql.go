@@ -2,28 +2,42 @@ package storage
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
+	"log/slog"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type MySQLStorage struct {
 	DB *sql.DB
 }
 
-func NewMySQLStorage(user, pass, host, dbname string) (*MySQLStorage, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true",
-		user, pass, host, dbname,
-	)
+// OpenMySQL connects to the database named by a go-sql-driver/mysql DSN,
+// e.g. "crawler:secret@tcp(localhost:3306)/crawlerdb". Time columns are
+// always parsed into time.Time, and updates report matched rather than
+// changed rows.
+func OpenMySQL(dsn string) (*MySQLStorage, error) {
+	if dsn == "" {
+		return nil, errors.New("no database DSN configured (set database.dsn in the config file or GOCRAWLER_DSN)")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = true
+	cfg.ClientFoundRows = true
 
-	db, err := sql.Open("mysql", dsn)
+	connector, err := mysql.NewConnector(cfg)
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(connector)
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
+	slog.Debug("mysql connected", "addr", cfg.Addr, "db", cfg.DBName)
 	return &MySQLStorage{DB: db}, nil
 }
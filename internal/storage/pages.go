@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PageLink is an edge of the link graph as stored in the links table.
+type PageLink struct {
+	TargetURL  string
+	AnchorText string
+	Rel        []string
+	Element    string
+	Depth      int // discovery depth: clicks from the seed to the target
+}
+
+// Page is a crawled page as stored in the pages table.
+type Page struct {
+	URL         string
+	Title       string
+	Description string
+	Lang        string
+	Text        string // main readable text
+	Hreflang    string // the page's own hreflang among its variants
+	Variant     string // key URL of its variant cluster, "" without variants
+	Depth       int    // clicks from the seed
+}
+
+// PageVariant is a page of a variant cluster.
+type PageVariant struct {
+	Group    string // SHA-256 of the cluster's key URL
+	URL      string
+	Hreflang string
+	Lang     string
+}
+
+type PageCount struct {
+	URL   string
+	Count int
+}
+
+type PageRepository struct {
+	db *MySQLStorage
+}
+
+func NewPageRepository(store *MySQLStorage) *PageRepository {
+	return &PageRepository{db: store}
+}
+
+func URLHash(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:])
+}
+
+// SavePage upserts a crawled page and returns its id. A page reached again
+// keeps the smallest depth seen and belongs to the crawl that fetched it
+// last; crawlID 0 stores no crawl.
+func (repo *PageRepository) SavePage(ctx context.Context, crawlID int64, p Page) (int64, error) {
+	query := `
+        INSERT INTO pages (crawl_id, url, url_hash, title, description, lang, body_text, hreflang, variant_group, depth)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), crawl_id = VALUES(crawl_id), title = VALUES(title),
+            description = VALUES(description), lang = VALUES(lang), body_text = VALUES(body_text),
+            hreflang = VALUES(hreflang), variant_group = VALUES(variant_group), depth = LEAST(depth, VALUES(depth))
+    `
+
+	group := ""
+	if p.Variant != "" {
+		group = URLHash(p.Variant)
+	}
+	res, err := repo.db.DB.ExecContext(ctx, query, nullID(crawlID), p.URL, URLHash(p.URL), p.Title, p.Description, p.Lang, p.Text, p.Hreflang, group, p.Depth)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// InsertLinks records the outgoing links of a page, replacing any edges
+// stored for it by an earlier visit.
+func (repo *PageRepository) InsertLinks(ctx context.Context, sourceID int64, links []PageLink) error {
+	tx, err := repo.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM links WHERE source_page_id = ?", sourceID); err != nil {
+		return err
+	}
+
+	const batch = 200
+	for start := 0; start < len(links); start += batch {
+		end := min(start+batch, len(links))
+
+		query := "INSERT INTO links (source_page_id, target_url, target_hash, anchor_text, rel, element, depth) VALUES "
+		args := make([]interface{}, 0, (end-start)*7)
+		for i, l := range links[start:end] {
+			if i > 0 {
+				query += ", "
+			}
+			query += "(?, ?, ?, ?, ?, ?, ?)"
+			args = append(args, sourceID, l.TargetURL, URLHash(l.TargetURL), l.AnchorText, strings.Join(l.Rel, " "), l.Element, l.Depth)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// InboundLinkCounts lists link targets by the number of distinct pages
+// linking to them, most linked first.
+func (repo *PageRepository) InboundLinkCounts(ctx context.Context, limit int) ([]PageCount, error) {
+	query := `
+        SELECT MIN(l.target_url), COUNT(DISTINCT l.source_page_id) AS inbound
+        FROM links l
+        WHERE l.element <> 'img'
+        GROUP BY l.target_hash
+        ORDER BY inbound DESC
+        LIMIT ?
+    `
+
+	rows, err := repo.db.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PageCount
+	for rows.Next() {
+		var c PageCount
+		if err := rows.Scan(&c.URL, &c.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// OrphanPages returns crawled pages, other than seeds, that no other
+// crawled page links to.
+func (repo *PageRepository) OrphanPages(ctx context.Context) ([]string, error) {
+	query := `
+        SELECT p.url FROM pages p
+        WHERE p.depth > 0 AND NOT EXISTS (
+            SELECT 1 FROM links l
+            WHERE l.target_hash = p.url_hash AND l.source_page_id <> p.id AND l.element <> 'img'
+        )
+        ORDER BY p.url
+    `
+
+	rows, err := repo.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// ClickPath finds the shortest chain of links from one of the seeds (pages
+// at depth 0, or from if set) to target, searching at most maxHops levels.
+// It returns nil if target is unreachable.
+func (repo *PageRepository) ClickPath(ctx context.Context, from, target string, maxHops int) ([]string, error) {
+	var sources []string
+	if from != "" {
+		sources = []string{from}
+	} else {
+		rows, err := repo.db.DB.QueryContext(ctx, "SELECT url FROM pages WHERE depth = 0")
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var u string
+			if err := rows.Scan(&u); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			sources = append(sources, u)
+		}
+		rows.Close()
+	}
+
+	return clickPath(sources, target, maxHops, func(level []string) ([][2]string, error) {
+		var links [][2]string
+		for start := 0; start < len(level); start += 500 {
+			chunk := level[start:min(start+500, len(level))]
+			query := `SELECT p.url, l.target_url FROM links l JOIN pages p ON p.id = l.source_page_id
+                      WHERE l.element <> 'img' AND p.url_hash IN (?` + strings.Repeat(", ?", len(chunk)-1) + `)`
+			args := make([]interface{}, len(chunk))
+			for i, u := range chunk {
+				args[i] = URLHash(u)
+			}
+
+			rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var src, dst string
+				if err := rows.Scan(&src, &dst); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				links = append(links, [2]string{src, dst})
+			}
+			rows.Close()
+		}
+		return links, nil
+	})
+}
+
+// clickPath is the breadth-first search behind ClickPath. outLinks returns
+// the (source, target) links leaving the pages of one level.
+func clickPath(sources []string, target string, maxHops int, outLinks func(level []string) ([][2]string, error)) ([]string, error) {
+	parent := make(map[string]string, len(sources))
+	level := make([]string, 0, len(sources))
+	for _, s := range sources {
+		parent[s] = ""
+		level = append(level, s)
+	}
+
+	// each pass adds the pages one hop further away, so after maxHops
+	// passes every page in parent is at most maxHops links from a source
+	for hop := 0; hop < maxHops && len(level) > 0; hop++ {
+		if _, ok := parent[target]; ok {
+			break
+		}
+		links, err := outLinks(level)
+		if err != nil {
+			return nil, err
+		}
+		var next []string
+		for _, l := range links {
+			if _, seen := parent[l[1]]; !seen {
+				parent[l[1]] = l[0]
+				next = append(next, l[1])
+			}
+		}
+		level = next
+	}
+
+	if _, ok := parent[target]; !ok {
+		return nil, nil
+	}
+	var path []string
+	for u := target; u != ""; u = parent[u] {
+		path = append([]string{u}, path...)
+	}
+	return path, nil
+}
+
+// Languages lists the distinct languages of the stored pages.
+func (repo *PageRepository) Languages(ctx context.Context) ([]string, error) {
+	rows, err := repo.db.DB.QueryContext(ctx, "SELECT DISTINCT lang FROM pages WHERE lang <> '' ORDER BY lang")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var lang string
+		if err := rows.Scan(&lang); err != nil {
+			return nil, err
+		}
+		out = append(out, lang)
+	}
+	return out, rows.Err()
+}
+
+// Variants returns the stored pages that belong to a variant cluster,
+// ordered by cluster and hreflang; crawlID 0 means every crawl.
+func (repo *PageRepository) Variants(ctx context.Context, crawlID int64) ([]PageVariant, error) {
+	query := "SELECT variant_group, url, hreflang, lang FROM pages WHERE variant_group <> ''"
+	var args []interface{}
+	if crawlID != 0 {
+		query += " AND crawl_id = ?"
+		args = append(args, crawlID)
+	}
+	query += " ORDER BY variant_group, hreflang, url"
+
+	rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PageVariant
+	for rows.Next() {
+		var v PageVariant
+		if err := rows.Scan(&v.Group, &v.URL, &v.Hreflang, &v.Lang); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestClickPath(t *testing.T) {
+	// a -> b -> c -> d, a -> e -> d, x -> y
+	graph := map[string][]string{
+		"a": {"b", "e"},
+		"b": {"c", "a"},
+		"c": {"d"},
+		"e": {"d"},
+		"x": {"y"},
+	}
+	outLinks := func(level []string) ([][2]string, error) {
+		var links [][2]string
+		for _, src := range level {
+			for _, dst := range graph[src] {
+				links = append(links, [2]string{src, dst})
+			}
+		}
+		return links, nil
+	}
+
+	tests := []struct {
+		sources []string
+		target  string
+		maxHops int
+		want    []string
+	}{
+		{[]string{"a"}, "a", 0, []string{"a"}},
+		{[]string{"a"}, "b", 0, nil},
+		{[]string{"a"}, "b", 1, []string{"a", "b"}},
+		{[]string{"a"}, "c", 1, nil},
+		{[]string{"a"}, "c", 2, []string{"a", "b", "c"}},
+		{[]string{"a"}, "d", 1, nil},
+		{[]string{"a"}, "d", 2, []string{"a", "e", "d"}},
+		{[]string{"a"}, "d", 10, []string{"a", "e", "d"}},
+		{[]string{"a", "x"}, "y", 1, []string{"x", "y"}},
+		{[]string{"a"}, "y", 10, nil},
+		{nil, "a", 10, nil},
+	}
+	for _, tt := range tests {
+		got, err := clickPath(tt.sources, tt.target, tt.maxHops, outLinks)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("clickPath(%v, %s, %d) = %v, %v, want %v", tt.sources, tt.target, tt.maxHops, got, err, tt.want)
+		}
+	}
+
+	calls := 0
+	clickPath([]string{"a"}, "zzz", 3, func(level []string) ([][2]string, error) {
+		calls++
+		return outLinks(level)
+	})
+	if calls != 3 {
+		t.Errorf("levels expanded for maxHops 3: %d, want 3", calls)
+	}
+
+	boom := errors.New("boom")
+	if _, err := clickPath([]string{"a"}, "d", 2, func([]string) ([][2]string, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Errorf("clickPath error = %v, want %v", err, boom)
+	}
+}
@@ -13,13 +13,16 @@ func NewImageRepository(store *MySQLStorage) *ImageRepository {
 	return &ImageRepository{db: store}
 }
 
-func (repo *ImageRepository) InsertImage(ctx context.Context, meta *images.ImageMetadata) error {
+// InsertImage stores an image found by the crawl crawlID (0 for none).
+func (repo *ImageRepository) InsertImage(ctx context.Context, crawlID int64, meta *images.ImageMetadata) error {
 	query := `
-        INSERT INTO images (original_url, saved_path, thumb_path, filename, width, height, format)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
+        INSERT INTO images (crawl_id, original_url, saved_path, thumb_path, filename, width, height, format,
+                            page_url, alt_text, title, caption, heading, page_title, srcset_descriptor, sizes)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
 	_, err := repo.db.DB.ExecContext(ctx, query,
+		nullID(crawlID),
 		meta.OriginalURL,
 		meta.SavedPath,
 		meta.ThumbPath,
@@ -27,6 +30,14 @@ func (repo *ImageRepository) InsertImage(ctx context.Context, meta *images.Image
 		meta.Width,
 		meta.Height,
 		meta.Format,
+		meta.PageURL,
+		meta.AltText,
+		meta.Title,
+		meta.Caption,
+		meta.Heading,
+		meta.PageTitle,
+		meta.SrcsetDescriptor,
+		meta.Sizes,
 	)
 
 	return err
@@ -34,7 +45,9 @@ func (repo *ImageRepository) InsertImage(ctx context.Context, meta *images.Image
 
 func (repo *ImageRepository) SearchImages(ctx context.Context, params map[string]string) ([]images.ImageMetadata, error) {
 
-	base := "SELECT original_url, saved_path, thumb_path, filename, width, height, format FROM images WHERE 1=1"
+	base := `SELECT original_url, saved_path, thumb_path, filename, width, height, format,
+	                page_url, alt_text, title, caption, heading, page_title, srcset_descriptor, sizes
+	         FROM images WHERE 1=1`
 	args := []interface{}{}
 
 	if v, ok := params["format"]; ok && v != "" {
@@ -49,6 +62,25 @@ func (repo *ImageRepository) SearchImages(ctx context.Context, params map[string
 		base += " AND original_url LIKE ?"
 		args = append(args, "%"+v+"%")
 	}
+	if v, ok := params["crawl"]; ok && v != "" {
+		base += " AND crawl_id = ?"
+		args = append(args, v)
+	}
+	if params["field"] != "" || params["value"] != "" {
+		cond, fargs := fieldCondition(FieldFilter{Name: params["field"], Value: params["value"]})
+		base += ` AND EXISTS (SELECT 1 FROM pages p JOIN page_fields f ON f.page_id = p.id
+		                      WHERE p.url_hash = SHA2(images.page_url, 256)` + cond + `)`
+		args = append(args, fargs...)
+	}
+	if v, ok := params["lang"]; ok && v != "" {
+		base += " AND EXISTS (SELECT 1 FROM pages p WHERE p.url_hash = SHA2(images.page_url, 256) AND p.lang = ?)"
+		args = append(args, v)
+	}
+	if v, ok := params["q"]; ok && v != "" {
+		base += " AND (alt_text LIKE ? OR title LIKE ? OR caption LIKE ? OR heading LIKE ? OR page_title LIKE ?)"
+		like := "%" + v + "%"
+		args = append(args, like, like, like, like, like)
+	}
 
 	rows, err := repo.db.DB.QueryContext(ctx, base, args...)
 	if err != nil {
@@ -68,6 +100,14 @@ func (repo *ImageRepository) SearchImages(ctx context.Context, params map[string
 			&m.Width,
 			&m.Height,
 			&m.Format,
+			&m.PageURL,
+			&m.AltText,
+			&m.Title,
+			&m.Caption,
+			&m.Heading,
+			&m.PageTitle,
+			&m.SrcsetDescriptor,
+			&m.Sizes,
 		)
 		if err != nil {
 			return nil, err
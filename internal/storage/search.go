@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// PageQuery is a full-text search over the stored pages.
+type PageQuery struct {
+	Text    string // MySQL FULLTEXT query; boolean mode when a word starts with + - ~ < > ( " or ends with * ) "
+	Anchor  string // PageHit.Text is taken around its first occurrence; "" for the start of the text
+	Lang    string // only pages in this language when set
+	CrawlID int64  // only pages of this crawl when set
+	Limit   int
+	Offset  int
+}
+
+// PageHit is a page matching a PageQuery.
+type PageHit struct {
+	URL         string
+	Title       string
+	Description string
+	Lang        string
+	Text        string // up to excerptChars characters of the page text
+	TextBefore  bool   // the page text goes on before Text
+	TextAfter   bool   // and after it
+	CrawlID     int64
+	Score       float64
+}
+
+// A PageHit's text excerpt: its length, and how much of it comes before
+// the anchor, in characters.
+const (
+	excerptChars  = 800
+	excerptBefore = 200
+)
+
+// booleanQuery reports whether q uses full-text boolean operators. They
+// only count at the start or end of a word, so "e-mail" is not "e NOT mail".
+func booleanQuery(q string) bool {
+	for _, w := range strings.Fields(q) {
+		if strings.ContainsAny(w[:1], `+-~<>("`) || strings.ContainsAny(w[len(w)-1:], `*)"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchPages returns the pages whose title, description or text match
+// q.Text, best matches first. It needs the ft_pages FULLTEXT index.
+func (repo *PageRepository) SearchPages(ctx context.Context, q PageQuery) ([]PageHit, error) {
+	match := "MATCH(title, description, body_text) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	if booleanQuery(q.Text) {
+		match = "MATCH(title, description, body_text) AGAINST (? IN BOOLEAN MODE)"
+	}
+	// the excerpt starts excerptBefore characters before the anchor
+	start := "GREATEST(1, LOCATE(?, body_text) - " + strconv.Itoa(excerptBefore) + ")"
+	query := `SELECT url, title, description, lang,
+                     SUBSTRING(body_text, ` + start + `, ` + strconv.Itoa(excerptChars) + `),
+                     ` + start + ` > 1,
+                     CHAR_LENGTH(body_text) >= ` + start + ` + ` + strconv.Itoa(excerptChars) + `,
+                     COALESCE(crawl_id, 0), ` + match + ` AS score
+              FROM pages WHERE ` + match
+	args := []interface{}{q.Anchor, q.Anchor, q.Anchor, q.Text, q.Text}
+	if q.Lang != "" {
+		query += " AND lang = ?"
+		args = append(args, q.Lang)
+	}
+	if q.CrawlID != 0 {
+		query += " AND crawl_id = ?"
+		args = append(args, q.CrawlID)
+	}
+	query += " ORDER BY score DESC, id LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := repo.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PageHit
+	for rows.Next() {
+		var h PageHit
+		if err := rows.Scan(&h.URL, &h.Title, &h.Description, &h.Lang, &h.Text, &h.TextBefore, &h.TextAfter, &h.CrawlID, &h.Score); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
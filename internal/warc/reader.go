@@ -0,0 +1,112 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader reads records from a WARC file, gzip'd (one member per record or
+// one for the whole file) or uncompressed.
+type Reader struct {
+	br    *bufio.Reader
+	pos   int64 // bytes consumed from br
+	start int64 // where the record last returned by Next starts in br
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br) // reads concatenated members as one stream
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &Reader{br: br}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one.
+func (r *Reader) Next() (*Record, error) {
+	var version string
+	for {
+		line, err := r.br.ReadString('\n')
+		r.pos += int64(len(line))
+		if err != nil {
+			if errors.Is(err, io.EOF) && strings.TrimSpace(line) == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue // padding between records
+		}
+		r.start = r.pos - int64(len(line))
+		version = strings.TrimSpace(line)
+		break
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("warc: bad version line %q", version)
+	}
+
+	rec := &Record{}
+	length := -1
+	for {
+		line, err := r.br.ReadString('\n')
+		r.pos += int64(len(line))
+		if err != nil {
+			return nil, fmt.Errorf("warc: reading header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		rec.Header = append(rec.Header, Field{name, value})
+		if strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("warc: bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("warc: record without Content-Length")
+	}
+
+	rec.Block = make([]byte, length)
+	n, err := io.ReadFull(r.br, rec.Block)
+	r.pos += int64(n)
+	if err != nil {
+		return nil, fmt.Errorf("warc: reading block: %w", err)
+	}
+	return rec, nil
+}
+
+// TargetURI returns WARC-Target-URI, tolerating the <...> form some WARC/1.0
+// writers used.
+func (r *Record) TargetURI() string {
+	return strings.Trim(r.Get("WARC-Target-URI"), "<>")
+}
+
+// httpPayload returns the body after the HTTP headers of a response block.
+func httpPayload(block []byte) []byte {
+	if i := bytes.Index(block, []byte("\r\n\r\n")); i >= 0 {
+		return block[i+4:]
+	}
+	return nil
+}
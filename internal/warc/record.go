@@ -0,0 +1,97 @@
+package warc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Record types written by the crawler.
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+	TypeResource = "resource"
+	TypeRevisit  = "revisit"
+)
+
+// Record is a single WARC record. Header keys keep the order they were
+// added in; Content-Length is computed from Block when writing.
+type Record struct {
+	Header []Field
+	Block  []byte
+}
+
+type Field struct {
+	Name  string
+	Value string
+}
+
+func (r *Record) Set(name, value string) {
+	for i, f := range r.Header {
+		if f.Name == name {
+			r.Header[i].Value = value
+			return
+		}
+	}
+	r.Header = append(r.Header, Field{name, value})
+}
+
+func (r *Record) Get(name string) string {
+	for _, f := range r.Header {
+		if equalFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func (r *Record) Type() string { return r.Get("WARC-Type") }
+
+// NewRecord returns a record with a fresh ID and the current date.
+func NewRecord(typ string) *Record {
+	r := &Record{}
+	r.Set("WARC-Type", typ)
+	r.Set("WARC-Record-ID", NewRecordID())
+	r.Set("WARC-Date", time.Now().UTC().Format(time.RFC3339Nano))
+	return r
+}
+
+func NewRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Digest formats a WARC-*-Digest value.
+func Digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// WriteTo serializes the record as WARC/1.1 (uncompressed).
+func (r *Record) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString("WARC/1.1\r\n")
+	for _, f := range r.Header {
+		if equalFold(f.Name, "Content-Length") {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s: %s\r\n", f.Name, f.Value)
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(r.Block))
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+	return buf.WriteTo(w)
+}
+
+func equalFold(a, b string) bool {
+	return bytes.EqualFold([]byte(a), []byte(b))
+}
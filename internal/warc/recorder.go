@@ -0,0 +1,159 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Recorder is an http.RoundTripper that archives every request/response
+// pair it sees as request, response and metadata records. The archive keeps
+// the body as the server sent it; the caller gets it decoded, as from Go's
+// own transport, unless it set Accept-Encoding itself.
+type Recorder struct {
+	Next   http.RoundTripper
+	Writer *Writer
+
+	// OnError is called when archiving fails; the response is still
+	// returned to the caller. Defaults to logging the error.
+	OnError func(url string, err error)
+}
+
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	// Ask for an uncompressed body so the archived payload is what the
+	// server sent, not Go's transparently decompressed copy. This turns off
+	// that decompression, so a server that compresses anyway is decoded
+	// below.
+	decode := req.Header.Get("Accept-Encoding") == ""
+	if decode {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+
+	start := time.Now()
+	resp, err := rec.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := rec.archive(req, resp, body, start, elapsed); err != nil {
+		if rec.OnError != nil {
+			rec.OnError(req.URL.String(), err)
+		} else {
+			slog.Error("warc write failed", "url", req.URL.String(), "err", err)
+		}
+	}
+	if decode {
+		if err := decodeContent(resp); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", req.URL, err)
+		}
+	}
+	return resp, nil
+}
+
+// decodeContent replaces resp.Body with its decoded form when the response
+// has a gzip or deflate Content-Encoding, and drops the headers describing
+// the encoded body. Other encodings are an error: nobody could parse the
+// body.
+func decodeContent(resp *http.Response) error {
+	var r io.Reader
+	switch enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); enc {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		r = zr
+	case "deflate":
+		// deflate should be zlib-wrapped, but some servers send a raw
+		// deflate stream
+		br := bufio.NewReader(resp.Body)
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return err
+			}
+			r = zr
+		} else {
+			r = flate.NewReader(br)
+		}
+	default:
+		return fmt.Errorf("unsupported Content-Encoding %q", enc)
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{r, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+func (rec *Recorder) archive(req *http.Request, resp *http.Response, body []byte, start time.Time, elapsed time.Duration) error {
+	target := req.URL.String()
+	date := start.UTC().Format(time.RFC3339Nano)
+
+	var respBlock bytes.Buffer
+	fmt.Fprintf(&respBlock, "%s %s\r\n", statusProto(resp), resp.Status)
+	resp.Header.Write(&respBlock)
+	respBlock.WriteString("\r\n")
+	respBlock.Write(body)
+
+	response := NewRecord(TypeResponse)
+	response.Set("WARC-Date", date)
+	response.Set("WARC-Target-URI", target)
+	response.Set("Content-Type", "application/http;msgtype=response")
+	response.Set("WARC-Payload-Digest", Digest(body))
+	response.Set("WARC-Block-Digest", Digest(respBlock.Bytes()))
+	response.Block = respBlock.Bytes()
+	responseID := response.Get("WARC-Record-ID")
+
+	var reqBlock bytes.Buffer
+	if err := req.Write(&reqBlock); err != nil {
+		return err
+	}
+	request := NewRecord(TypeRequest)
+	request.Set("WARC-Date", date)
+	request.Set("WARC-Target-URI", target)
+	request.Set("WARC-Concurrent-To", responseID)
+	request.Set("Content-Type", "application/http;msgtype=request")
+	request.Set("WARC-Block-Digest", Digest(reqBlock.Bytes()))
+	request.Block = reqBlock.Bytes()
+
+	metadata := NewRecord(TypeMetadata)
+	metadata.Set("WARC-Date", date)
+	metadata.Set("WARC-Target-URI", target)
+	metadata.Set("WARC-Concurrent-To", responseID)
+	metadata.Set("Content-Type", "application/warc-fields")
+	metadata.Block = fmt.Appendf(nil, "fetchTimeMs: %d\r\n", elapsed.Milliseconds())
+
+	return rec.Writer.Write(response, request, metadata)
+}
+
+// statusProto is the protocol as written in a status line: HTTP/1.1, but
+// HTTP/2 and HTTP/3 without a minor version.
+func statusProto(resp *http.Response) string {
+	if resp.ProtoMajor >= 2 {
+		return fmt.Sprintf("HTTP/%d", resp.ProtoMajor)
+	}
+	return fmt.Sprintf("HTTP/%d.%d", resp.ProtoMajor, resp.ProtoMinor)
+}
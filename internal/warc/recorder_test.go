@@ -0,0 +1,175 @@
+package warc
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const page = "<html><body>hello</body></html>"
+
+func encode(t *testing.T, enc string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch enc {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	default:
+		return []byte(page)
+	}
+	w.Write([]byte(page))
+	w.Close()
+	return buf.Bytes()
+}
+
+// TestRecordAndReplay serves compressed bodies to a client that asked for
+// identity and checks that the caller and the replay get the page decoded
+// while the archive keeps the bytes the server sent.
+func TestRecordAndReplay(t *testing.T) {
+	tests := []struct {
+		path, header, body string // body: encoding of the served body
+	}{
+		{"/plain", "", ""},
+		{"/gzip", "gzip", "gzip"},
+		{"/deflate", "deflate", "deflate"},
+		{"/raw-deflate", "deflate", "raw-deflate"},
+	}
+	bodies := map[string][]byte{}
+	for _, tt := range tests {
+		bodies[tt.path] = encode(t, tt.body)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, tt := range tests {
+			if tt.path == r.URL.Path && tt.header != "" {
+				w.Header().Set("Content-Encoding", tt.header)
+			}
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(bodies[r.URL.Path])
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writer, err := NewWriter(dir, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &Recorder{Next: http.DefaultTransport, Writer: writer}}
+	for _, tt := range tests {
+		resp, err := client.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		got, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(got) != page {
+			t.Errorf("GET %s: body %q, err %v, want %q", tt.path, got, err, page)
+		}
+		if ce := resp.Header.Get("Content-Encoding"); ce != "" {
+			t.Errorf("GET %s: Content-Encoding %q left on the decoded body", tt.path, ce)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := LoadArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		block, ok, err := archive.block(srv.URL + tt.path)
+		if !ok || err != nil {
+			t.Fatalf("%s not archived: %v", tt.path, err)
+		}
+		if !bytes.HasSuffix(block, bodies[tt.path]) {
+			t.Errorf("%s: archived body is not what the server sent", tt.path)
+		}
+
+		req, _ := http.NewRequest("GET", srv.URL+tt.path, nil)
+		resp, err := archive.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("replay %s: %v", tt.path, err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		if string(got) != page {
+			t.Errorf("replay %s: body %q, want %q", tt.path, got, page)
+		}
+	}
+}
+
+func TestDecodeContentUnsupported(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": {"br"}},
+		Body:   io.NopCloser(strings.NewReader("x")),
+	}
+	if err := decodeContent(resp); err == nil {
+		t.Error("decodeContent(br): got nil error, want unsupported encoding")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// TestRecordHTTP2 checks that an HTTP/2 response is archived with an
+// "HTTP/2" status line and still replays.
+func TestRecordHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewWriter(dir, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2 := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: 200,
+			Proto:      "HTTP/2.0",
+			ProtoMajor: 2,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       io.NopCloser(strings.NewReader(page)),
+			Request:    req,
+		}, nil
+	})
+	client := &http.Client{Transport: &Recorder{Next: h2, Writer: writer}}
+	resp, err := client.Get("https://example.com/h2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := LoadArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _, err := archive.block("https://example.com/h2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "HTTP/2 200 OK\r\n"; !bytes.HasPrefix(block, []byte(want)) {
+		t.Errorf("status line %q, want %q", bytes.SplitAfter(block, []byte("\n"))[0], want)
+	}
+	req, _ := http.NewRequest("GET", "https://example.com/h2", nil)
+	resp, err = archive.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got, _ := io.ReadAll(resp.Body); resp.StatusCode != 200 || string(got) != page {
+		t.Errorf("replay: %d %q, want 200 %q", resp.StatusCode, got, page)
+	}
+}
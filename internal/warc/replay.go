@@ -0,0 +1,272 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Archive serves HTTP responses from WARC files instead of the network.
+// It implements http.RoundTripper; URLs missing from the archive get a 404.
+// Only the position of each response is kept in memory; the record is
+// read from its file when the URL is requested.
+type Archive struct {
+	responses map[string]location // canonical URL -> response record
+
+	Hits   atomic.Int64
+	Misses atomic.Int64
+}
+
+// location is where a record starts: offset is the start of its gzip
+// member (or of the record in an uncompressed file) and skip the bytes
+// before the record once the member is decompressed.
+type location struct {
+	path         string
+	gzip         bool
+	offset, skip int64
+}
+
+// revisit is what resolving a revisit record needs from it.
+type revisit struct {
+	target, digest, refersTo string
+}
+
+// LoadArchive indexes the response (and revisit) records of the given WARC
+// files. Directories are searched for *.warc and *.warc.gz files.
+func LoadArchive(paths ...string) (*Archive, error) {
+	a := &Archive{responses: make(map[string]location)}
+
+	var files []string
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		for _, pattern := range []string{"*.warc", "*.warc.gz"} {
+			m, _ := filepath.Glob(filepath.Join(p, pattern))
+			files = append(files, m...)
+		}
+	}
+
+	byDigest := make(map[string]location) // payload digest -> response record
+	var revisits []revisit
+	for _, f := range files {
+		err := scan(f, func(rec *Record, loc location) {
+			if !strings.HasPrefix(rec.Get("Content-Type"), "application/http") {
+				return
+			}
+			switch rec.Type() {
+			case TypeResponse:
+				key := canonicalURL(rec.TargetURI())
+				if _, ok := a.responses[key]; !ok {
+					a.responses[key] = loc
+				}
+				digest := rec.Get("WARC-Payload-Digest")
+				if digest == "" {
+					digest = Digest(httpPayload(rec.Block))
+				}
+				byDigest[digest] = loc
+			case TypeRevisit:
+				revisits = append(revisits, revisit{
+					target:   rec.TargetURI(),
+					digest:   rec.Get("WARC-Payload-Digest"),
+					refersTo: strings.Trim(rec.Get("WARC-Refers-To-Target-URI"), "<>"),
+				})
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+	}
+
+	// Revisits reuse an earlier capture's payload.
+	for _, r := range revisits {
+		key := canonicalURL(r.target)
+		if _, ok := a.responses[key]; ok {
+			continue
+		}
+		if loc, ok := byDigest[r.digest]; ok {
+			a.responses[key] = loc
+		} else if loc, ok := a.responses[canonicalURL(r.refersTo)]; ok {
+			a.responses[key] = loc
+		}
+	}
+	return a, nil
+}
+
+// scan calls fn with every record of the WARC file at path and where it
+// starts. Gzip'd files are read one member at a time so that a record can
+// be found again by seeking to its member.
+func scan(path string, fn func(*Record, location)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cr := &countingReader{r: f}
+	br := bufio.NewReader(cr)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		r := &Reader{br: br}
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fn(rec, location{path: path, offset: r.start})
+		}
+	}
+
+	var zr *gzip.Reader
+	for {
+		member := cr.n - int64(br.Buffered())
+		if zr == nil {
+			zr, err = gzip.NewReader(br)
+		} else {
+			err = zr.Reset(br)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		zr.Multistream(false)
+		r := &Reader{br: bufio.NewReader(zr)}
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			fn(rec, location{path: path, gzip: true, offset: member, skip: r.start})
+		}
+	}
+}
+
+// read reads the record at l.
+func (l location) read() (*Record, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var r io.Reader = f
+	if l.gzip {
+		zr, err := gzip.NewReader(bufio.NewReader(f))
+		if err != nil {
+			return nil, err
+		}
+		zr.Multistream(false)
+		r = zr
+	}
+	if _, err := io.CopyN(io.Discard, r, l.skip); err != nil {
+		return nil, err
+	}
+	return (&Reader{br: bufio.NewReader(r)}).Next()
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (a *Archive) Len() int { return len(a.responses) }
+
+// block returns the HTTP response block archived for rawURL.
+func (a *Archive) block(rawURL string) ([]byte, bool, error) {
+	loc, ok := a.responses[canonicalURL(rawURL)]
+	if !ok {
+		return nil, false, nil
+	}
+	rec, err := loc.read()
+	if err != nil {
+		return nil, true, err
+	}
+	return rec.Block, true, nil
+}
+
+func (a *Archive) RoundTrip(req *http.Request) (*http.Response, error) {
+	block, ok, err := a.block(req.URL.String())
+	if !ok {
+		a.Misses.Add(1)
+		return &http.Response{
+			Status:     "404 Not Found (not in archive)",
+			StatusCode: http.StatusNotFound,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("not in archive")),
+			Request:    req,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", req.URL, err)
+	}
+	a.Hits.Add(1)
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(minorVersion(block))), req)
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", req.URL, err)
+	}
+	if err := decodeContent(resp); err != nil {
+		return nil, fmt.Errorf("replay %s: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+// minorVersion rewrites an "HTTP/2" or "HTTP/3" status line to the
+// "HTTP/2.0" form http.ReadResponse accepts.
+func minorVersion(block []byte) []byte {
+	if len(block) > 7 && bytes.HasPrefix(block, []byte("HTTP/")) && block[5] >= '2' && block[5] <= '9' && block[6] == ' ' {
+		return slices.Concat(block[:6], []byte(".0"), block[6:])
+	}
+	return block
+}
+
+func canonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+	u.Fragment = ""
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
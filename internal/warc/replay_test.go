@@ -0,0 +1,104 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func response(target, body string) *Record {
+	r := NewRecord(TypeResponse)
+	r.Set("WARC-Target-URI", target)
+	r.Set("Content-Type", "application/http;msgtype=response")
+	r.Set("WARC-Payload-Digest", Digest([]byte(body)))
+	r.Block = []byte("HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\n" + body)
+	return r
+}
+
+// TestArchiveLayouts indexes the same records written uncompressed, as
+// one gzip member per record and as one member for the whole file, and
+// checks that each is read back from its offset.
+func TestArchiveLayouts(t *testing.T) {
+	info := NewRecord(TypeWarcinfo)
+	info.Set("Content-Type", "application/warc-fields")
+	info.Block = []byte("software: test\r\n")
+	visit := NewRecord(TypeRevisit)
+	visit.Set("WARC-Target-URI", "https://example.com/again")
+	visit.Set("Content-Type", "application/http;msgtype=response")
+	visit.Set("WARC-Payload-Digest", Digest([]byte("first")))
+	visit.Block = []byte("HTTP/1.1 200 OK\r\n\r\n")
+	recs := []*Record{
+		info,
+		response("https://example.com/", "first"),
+		response("https://example.com/b", "second"),
+		response("https://example.com/", "later capture"),
+		visit,
+	}
+
+	layouts := map[string]func(io.Writer){
+		"plain.warc": func(w io.Writer) {
+			for _, r := range recs {
+				r.WriteTo(w)
+			}
+		},
+		"members.warc.gz": func(w io.Writer) {
+			for _, r := range recs {
+				zw := gzip.NewWriter(w)
+				r.WriteTo(zw)
+				zw.Close()
+			}
+		},
+		"whole.warc.gz": func(w io.Writer) {
+			zw := gzip.NewWriter(w)
+			for _, r := range recs {
+				r.WriteTo(zw)
+			}
+			zw.Close()
+		},
+	}
+	want := map[string]string{
+		"https://example.com/":      "first",
+		"https://example.com/b":     "second",
+		"https://example.com/again": "first",
+	}
+	for name, write := range layouts {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			write(&buf)
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			archive, err := LoadArchive(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := archive.Len(); got != len(want) {
+				t.Errorf("Len = %d, want %d", got, len(want))
+			}
+			for u, body := range want {
+				block, ok, err := archive.block(u)
+				if !ok || err != nil {
+					t.Errorf("block(%s): ok %v, err %v", u, ok, err)
+					continue
+				}
+				if got := string(httpPayload(block)); got != body {
+					t.Errorf("block(%s) payload %q, want %q", u, got, body)
+				}
+			}
+
+			req, _ := http.NewRequest("GET", "https://example.com/missing", nil)
+			resp, err := archive.RoundTrip(req)
+			if err != nil || resp.StatusCode != http.StatusNotFound {
+				t.Errorf("RoundTrip(missing) = %v, %v, want 404", resp, err)
+			}
+			if archive.Misses.Load() != 1 {
+				t.Errorf("Misses = %d, want 1", archive.Misses.Load())
+			}
+		})
+	}
+}